@@ -0,0 +1,274 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/harryosmar/protobuf-go/config"
+	"github.com/harryosmar/protobuf-go/database"
+	"github.com/harryosmar/protobuf-go/handlers"
+	"github.com/harryosmar/protobuf-go/logger"
+	"github.com/harryosmar/protobuf-go/middleware"
+	"github.com/harryosmar/protobuf-go/secrets"
+	"github.com/harryosmar/protobuf-go/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+)
+
+// shutdownTimeout bounds graceful shutdown of the servers and module hooks
+const shutdownTimeout = 30 * time.Second
+
+// App is an assembled server, created by Builder.Build
+type App struct {
+	cfg             *config.Config
+	logger          *zap.Logger
+	registry        *prometheus.Registry
+	httpMetrics     *middleware.HTTPMetrics
+	db              *gorm.DB
+	ownsDB          bool
+	modules         []Module
+	reloader        *config.Reloader
+	rateLimiter     *middleware.RateLimiter
+	healthChecker   *handlers.HealthChecker
+	grpcServer      *grpc.Server
+	debugLogToken   *secrets.Secret
+	adminToken      *secrets.Secret
+	shutdownTracing tracing.ShutdownFunc
+	closeOnce       sync.Once
+	closeErr        error
+}
+
+// Config returns the latest configuration, including reloads
+func (a *App) Config() *config.Config {
+	if a.reloader == nil {
+		return a.cfg
+	}
+	return a.reloader.Current()
+}
+
+// Logger returns the base logger
+func (a *App) Logger() *zap.Logger {
+	return a.logger
+}
+
+// Registry returns the Prometheus registry served on /metrics
+func (a *App) Registry() *prometheus.Registry {
+	return a.registry
+}
+
+// DB returns the database shared by the modules, nil with STORAGE_BACKEND=memory
+func (a *App) DB() *gorm.DB {
+	return a.db
+}
+
+// Reloader returns the configuration reloader
+func (a *App) Reloader() *config.Reloader {
+	return a.reloader
+}
+
+// GRPCServer returns the gRPC server with the interceptor chain and every module registered
+func (a *App) GRPCServer() *grpc.Server {
+	return a.grpcServer
+}
+
+// Run serves gRPC, the HTTP gateway and the optional admin server until ctx is done or
+// a server fails, then shuts everything down gracefully. SIGHUP reloads the configuration
+// and SIGUSR1 toggles debug logging while running.
+func (a *App) Run(ctx context.Context) error {
+	cfg := a.cfg
+	a.logger.Info("Starting server",
+		zap.String("app_name", cfg.AppName),
+		zap.String("app_version", cfg.AppVersion),
+		zap.String("grpc_port", cfg.GRPCPort),
+		zap.String("http_port", cfg.HTTPPort),
+		zap.String("admin_port", cfg.AdminPort),
+	)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// SIGHUP reloads the configuration, SIGUSR1 toggles debug logging
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	usr1 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+	defer signal.Stop(usr1)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				a.reloader.Reload("sighup")
+			case <-usr1:
+				a.logger.Info("SIGUSR1 received, log level changed", zap.Stringer("level", logger.ToggleDebug()))
+			}
+		}
+	}()
+
+	// Reload when the config file changes
+	go a.reloader.Watch(ctx, cfg.ConfigWatchInterval)
+
+	// Re-resolve token secret references so rotated tokens apply without a restart
+	a.watchTokens(ctx, cfg.SecretsRefreshInterval)
+
+	lis, err := net.Listen("tcp", cfg.GRPCPort)
+	if err != nil {
+		a.close()
+		return err
+	}
+	gatewayHandler, err := a.HTTPHandler(ctx, "localhost"+cfg.GRPCPort)
+	if err != nil {
+		lis.Close()
+		a.close()
+		return err
+	}
+
+	errs := make(chan error, 3)
+	go func() {
+		a.logger.Info("gRPC server listening", zap.String("port", cfg.GRPCPort))
+		errs <- a.grpcServer.Serve(lis)
+	}()
+
+	httpServer := &http.Server{Addr: cfg.HTTPPort, Handler: gatewayHandler}
+	go func() {
+		a.logger.Info("HTTP gateway listening", zap.String("port", cfg.HTTPPort))
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	// Start admin server when a separate admin port is configured
+	var adminServer *http.Server
+	if cfg.AdminPort != "" {
+		adminServer, err = a.newAdminServer()
+		if err != nil {
+			errs <- err
+		} else {
+			go func() {
+				errs <- a.serveAdmin(adminServer)
+			}()
+		}
+	}
+
+	// Wait for shutdown or server error
+	var runErr error
+	select {
+	case <-ctx.Done():
+		a.logger.Info("Shutdown signal received")
+	case runErr = <-errs:
+		a.logger.Error("Server error", zap.Error(runErr))
+	}
+
+	// Graceful shutdown
+	a.logger.Info("Shutting down servers...")
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer shutdownCancel()
+
+	a.stopGRPC(shutdownCtx)
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		a.logger.Error("Failed to shutdown HTTP gateway", zap.Error(err))
+	}
+	if adminServer != nil {
+		if err := adminServer.Shutdown(shutdownCtx); err != nil {
+			a.logger.Error("Failed to shutdown admin server", zap.Error(err))
+		}
+	}
+	cancel()
+
+	if err := a.Close(shutdownCtx); err != nil {
+		a.logger.Error("Shutdown hooks failed", zap.Error(err))
+	}
+	a.logger.Info("Servers shutdown completed")
+	return runErr
+}
+
+// watchTokens refreshes the admin and debug log tokens every interval until ctx is done
+func (a *App) watchTokens(ctx context.Context, interval time.Duration) {
+	tokens := map[string]*secrets.Secret{
+		"ADMIN_TOKEN":            a.adminToken,
+		"LOG_DEBUG_HEADER_TOKEN": a.debugLogToken,
+	}
+	for key, token := range tokens {
+		go token.Watch(ctx, interval,
+			func() {
+				a.logger.Info("Token rotated", zap.String("key", key), zap.String("secret", token.Reference()))
+			},
+			func(err error) {
+				a.logger.Warn("Failed to refresh token, keeping current one", zap.String("key", key), zap.Error(err))
+			},
+		)
+	}
+}
+
+// stopGRPC waits for in-flight RPCs to finish, forcing the stop when ctx expires
+func (a *App) stopGRPC(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		a.logger.Info("Gracefully stopping gRPC server...")
+		a.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		a.logger.Warn("Shutdown timeout exceeded, forcing gRPC server stop")
+		a.grpcServer.Stop()
+	}
+}
+
+// close releases resources with the default shutdown timeout
+func (a *App) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := a.Close(ctx); err != nil {
+		a.logger.Error("Shutdown hooks failed", zap.Error(err))
+	}
+}
+
+// Close runs the shutdown hooks of the initialized modules in reverse order, then closes
+// the database it opened and flushes traces and logs. Run calls it after the servers have
+// stopped; later calls return the first result.
+func (a *App) Close(ctx context.Context) error {
+	a.closeOnce.Do(func() {
+		a.closeErr = a.release(ctx)
+	})
+	return a.closeErr
+}
+
+// release frees everything Build initialized
+func (a *App) release(ctx context.Context) error {
+	var errs []error
+	for i := len(a.modules) - 1; i >= 0; i-- {
+		if err := a.modules[i].Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+			a.logger.Error("Module shutdown failed", zap.String("module", a.modules[i].Name()), zap.Error(err))
+		}
+	}
+
+	if a.ownsDB && a.db != nil {
+		if err := database.CloseDatabase(a.db); err != nil {
+			errs = append(errs, err)
+			a.logger.Error("Failed to close database", zap.Error(err))
+		}
+	}
+
+	if a.shutdownTracing != nil {
+		if err := a.shutdownTracing(ctx); err != nil {
+			errs = append(errs, err)
+			a.logger.Error("Failed to shutdown tracing", zap.Error(err))
+		}
+	}
+
+	a.logger.Sync()
+	return errors.Join(errs...)
+}
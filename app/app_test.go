@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAdminTokenRotation(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "admin-token")
+	if err := os.WriteFile(tokenFile, []byte("old-token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	app, err := NewBuilder([]string{
+		"--storage-backend=memory",
+		"--log-level=error",
+		"--admin-token=file://" + tokenFile,
+	}).Build(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close(context.Background())
+
+	handler := app.AdminHandler()
+	status := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if got := status("old-token"); got != http.StatusOK {
+		t.Fatalf("old token status = %d, want 200", got)
+	}
+
+	app.watchTokens(ctx, 10*time.Millisecond)
+	if err := os.WriteFile(tokenFile, []byte("new-token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for status("new-token") != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("rotated token was not accepted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := status("old-token"); got != http.StatusUnauthorized {
+		t.Errorf("old token status after rotation = %d, want 401", got)
+	}
+}
@@ -0,0 +1,184 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/harryosmar/protobuf-go/cache"
+	"github.com/harryosmar/protobuf-go/config"
+	"github.com/harryosmar/protobuf-go/database"
+	error2 "github.com/harryosmar/protobuf-go/error"
+	"github.com/harryosmar/protobuf-go/handlers"
+	"github.com/harryosmar/protobuf-go/logger"
+	"github.com/harryosmar/protobuf-go/metrics"
+	"github.com/harryosmar/protobuf-go/middleware"
+	"github.com/harryosmar/protobuf-go/secrets"
+	"github.com/harryosmar/protobuf-go/tracing"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Builder assembles an App from configuration and modules
+type Builder struct {
+	args    []string
+	cfg     *config.Config
+	db      *gorm.DB
+	modules []Module
+}
+
+// NewBuilder creates a builder that loads configuration from args, the config file and env
+func NewBuilder(args []string) *Builder {
+	return &Builder{args: args}
+}
+
+// WithConfig uses cfg instead of loading the configuration from args
+func (b *Builder) WithConfig(cfg *config.Config) *Builder {
+	b.cfg = cfg
+	return b
+}
+
+// WithDatabase uses db instead of opening DATABASE_URL; the caller stays responsible for closing it
+func (b *Builder) WithDatabase(db *gorm.DB) *Builder {
+	b.db = db
+	return b
+}
+
+// WithModules adds modules; they are migrated, initialized and registered in the order given
+func (b *Builder) WithModules(modules ...Module) *Builder {
+	b.modules = append(b.modules, modules...)
+	return b
+}
+
+// Build initializes logging, tracing, metrics and the database, runs the module migrations
+// and wires every module into the gRPC server, the gateway and the health endpoint. With
+// STORAGE_BACKEND=memory no database is opened or migrated and Deps.DB is nil.
+func (b *Builder) Build(ctx context.Context) (*App, error) {
+	seen := make(map[string]bool, len(b.modules))
+	for _, module := range b.modules {
+		if seen[module.Name()] {
+			return nil, fmt.Errorf("duplicate module %q", module.Name())
+		}
+		seen[module.Name()] = true
+	}
+
+	cfg := b.cfg
+	if cfg == nil {
+		loaded, err := config.Load(b.args)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration:\n%w", err)
+		}
+		cfg = loaded
+	}
+	error2.SetStackTraceEnabled(cfg.ErrorStackTraceEnabled)
+
+	// Initialize logger
+	baseLogger, err := logger.InitLogger(cfg.LogLevel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	logger.SetRedactStrategy(logger.RedactStrategy(cfg.LogRedactStrategy))
+
+	app := &App{cfg: cfg, logger: baseLogger}
+
+	// Release whatever was initialized when a later step fails
+	built := false
+	defer func() {
+		if !built {
+			app.Close(ctx)
+		}
+	}()
+
+	// Initialize tracing before any instrumented component is created
+	app.shutdownTracing, err = tracing.InitTracing(ctx, cfg, baseLogger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+
+	// Dedicated metrics registry with Go runtime, process and build info collectors
+	// Every App builds its own collectors, so apps in one process report separately
+	app.registry = metrics.NewRegistry(cfg.AppName, cfg.AppVersion)
+	app.httpMetrics = middleware.NewHTTPMetrics(app.registry)
+	cacheMetrics := cache.NewMetrics(app.registry)
+
+	// Resolve token secret references (file://, env://, vault://); Run re-resolves them
+	// on rotation
+	resolver := secrets.NewResolver(cfg.SecretsVaultFile)
+	if app.debugLogToken, err = resolver.NewSecret(ctx, cfg.LogDebugHeaderToken); err != nil {
+		return nil, fmt.Errorf("failed to resolve LOG_DEBUG_HEADER_TOKEN: %w", err)
+	}
+	if app.adminToken, err = resolver.NewSecret(ctx, cfg.AdminToken); err != nil {
+		return nil, fmt.Errorf("failed to resolve ADMIN_TOKEN: %w", err)
+	}
+
+	// Initialize the database unless STORAGE_BACKEND=memory; DATABASE_URL secret references
+	// are re-resolved on rotation
+	app.db = b.db
+	if app.db == nil && cfg.StorageBackend == "mysql" {
+		connectCtx, cancel := context.WithTimeout(ctx, cfg.DatabaseConnectTimeout)
+		app.db, err = database.NewDatabaseWithContext(connectCtx, cfg, baseLogger)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize database: %w", err)
+		}
+		app.ownsDB = true
+	}
+	if app.db != nil {
+		if err := database.RegisterMetrics(app.registry, app.db); err != nil {
+			return nil, fmt.Errorf("failed to register database metrics: %w", err)
+		}
+
+		// Migrate every module before any of them starts using the schema
+		for _, module := range b.modules {
+			if err := module.Migrate(app.db); err != nil {
+				return nil, fmt.Errorf("failed to migrate module %s: %w", module.Name(), err)
+			}
+		}
+	}
+
+	// Only initialized modules are registered and get their shutdown hook called
+	deps := Deps{Config: cfg, Logger: baseLogger, DB: app.db, Registry: app.registry, CacheMetrics: cacheMetrics}
+	for _, module := range b.modules {
+		if err := module.Init(deps); err != nil {
+			return nil, fmt.Errorf("failed to initialize module %s: %w", module.Name(), err)
+		}
+		app.modules = append(app.modules, module)
+	}
+
+	// Components that pick up configuration reloads
+	app.rateLimiter = middleware.NewConfiguredRateLimiter(cfg)
+	app.healthChecker = handlers.NewHealthChecker(cfg)
+	if app.db != nil {
+		app.healthChecker.AddCheck("database", func(ctx context.Context) error {
+			return database.Ping(ctx, app.db)
+		})
+	}
+	for _, module := range app.modules {
+		app.healthChecker.AddCheck(module.Name(), module.HealthCheck)
+	}
+
+	app.reloader = config.NewReloader(b.args, cfg, baseLogger, app.registry)
+	app.reloader.Subscribe("logger", func(cfg *config.Config) error {
+		logger.SetRedactStrategy(logger.RedactStrategy(cfg.LogRedactStrategy))
+		return logger.SetLevel(cfg.LogLevel)
+	})
+	app.reloader.Subscribe("rate_limiter", app.rateLimiter.ApplyConfig)
+	app.reloader.Subscribe("health", app.healthChecker.ApplyConfig)
+
+	app.grpcServer = app.newGRPCServer()
+	for _, module := range app.modules {
+		module.RegisterGRPC(app.grpcServer)
+	}
+
+	baseLogger.Info("Server built", zap.Strings("modules", moduleNames(app.modules)))
+	built = true
+	return app, nil
+}
+
+// moduleNames lists the names of modules for logging
+func moduleNames(modules []Module) []string {
+	names := make([]string, 0, len(modules))
+	for _, module := range modules {
+		names = append(names, module.Name())
+	}
+	return names
+}
@@ -0,0 +1,51 @@
+package app_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/harryosmar/protobuf-go/app"
+)
+
+func TestBuildFailsWhenDatabaseUnreachable(t *testing.T) {
+	// Accepts connections but never sends the MySQL handshake, like a blackholed server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				<-done
+				conn.Close()
+			}()
+		}
+	}()
+
+	const timeout = 300 * time.Millisecond
+	start := time.Now()
+	_, err = app.NewBuilder([]string{
+		"--storage-backend=mysql",
+		"--log-level=error",
+		"--database-url=user:secret@tcp(" + listener.Addr().String() + ")/app",
+		"--database-connect-timeout=" + timeout.String(),
+		"--database-retry-delay=100ms",
+	}).Build(context.Background())
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("Build succeeded, want a database error")
+	}
+	if elapsed > timeout+2*time.Second {
+		t.Errorf("Build failed after %v, want about DATABASE_CONNECT_TIMEOUT (%v)", elapsed, timeout)
+	}
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/harryosmar/protobuf-go/config"
+)
+
+// Main builds the server from the command line arguments and modules and runs it until
+// SIGINT or SIGTERM, exiting the process on failure. "config print" and "config validate"
+// inspect the configuration without starting the servers.
+//
+//	func main() {
+//		app.Main(modules.NewHelloModule(), modules.NewUserModule())
+//	}
+func Main(modules ...Module) {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(RunConfigCommand(os.Args[2:]))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	application, err := NewBuilder(os.Args[1:]).WithModules(modules...).Build(ctx)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to build server: %v", err)
+	}
+
+	if err := application.Run(ctx); err != nil {
+		stop()
+		os.Exit(1)
+	}
+}
+
+// RunConfigCommand prints or validates the effective configuration and returns the exit code
+func RunConfigCommand(args []string) int {
+	if len(args) == 0 || (args[0] != "print" && args[0] != "validate") {
+		fmt.Fprintln(os.Stderr, "usage: server config print|validate [--config file] [flags]")
+		return 2
+	}
+
+	cfg, err := config.Load(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		return 1
+	}
+
+	if args[0] == "validate" {
+		fmt.Println("Configuration is valid")
+		return 0
+	}
+	if err := cfg.Redacted().WriteYAML(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to print configuration: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/harryosmar/protobuf-go/cache"
+	"github.com/harryosmar/protobuf-go/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+)
+
+// Deps holds the shared components a module builds its repositories and usecases from
+type Deps struct {
+	Config       *config.Config
+	Logger       *zap.Logger
+	DB           *gorm.DB // nil with STORAGE_BACKEND=memory
+	Registry     prometheus.Registerer
+	CacheMetrics *cache.Metrics // counts lookups of the caches modules build, by cache name
+}
+
+// Module is a feature that plugs into the server: its gRPC service, gateway routes,
+// migrations, health check and shutdown hook. Embed BaseModule to implement only the
+// methods a module needs.
+type Module interface {
+	// Name identifies the module in logs and health check results
+	Name() string
+	// Init builds the module's dependencies; it runs after migrations
+	Init(deps Deps) error
+	// Migrate brings the module's schema up to date; it is not called without a database
+	Migrate(db *gorm.DB) error
+	// RegisterGRPC registers the module's gRPC services
+	RegisterGRPC(registrar grpc.ServiceRegistrar)
+	// RegisterGateway registers the module's HTTP gateway handlers that proxy to endpoint
+	RegisterGateway(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) error
+	// HealthCheck reports whether the module can serve requests
+	HealthCheck(ctx context.Context) error
+	// Shutdown releases the module's resources after the servers have stopped
+	Shutdown(ctx context.Context) error
+}
+
+// BaseModule implements every Module method except Name as a no-op
+type BaseModule struct{}
+
+// Init implements Module
+func (BaseModule) Init(deps Deps) error { return nil }
+
+// Migrate implements Module
+func (BaseModule) Migrate(db *gorm.DB) error { return nil }
+
+// RegisterGRPC implements Module
+func (BaseModule) RegisterGRPC(registrar grpc.ServiceRegistrar) {}
+
+// RegisterGateway implements Module
+func (BaseModule) RegisterGateway(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) error {
+	return nil
+}
+
+// HealthCheck implements Module
+func (BaseModule) HealthCheck(ctx context.Context) error { return nil }
+
+// Shutdown implements Module
+func (BaseModule) Shutdown(ctx context.Context) error { return nil }
@@ -0,0 +1,236 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/harryosmar/protobuf-go/handlers"
+	"github.com/harryosmar/protobuf-go/logger"
+	"github.com/harryosmar/protobuf-go/metrics"
+	"github.com/harryosmar/protobuf-go/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+// newGRPCServer creates the gRPC server with the production interceptor chain, keepalive and limits
+func (a *App) newGRPCServer() *grpc.Server {
+	cfg := a.cfg
+	grpcMetrics := middleware.NewGRPCMetrics(middleware.MetricsConfig{
+		DurationBuckets:  cfg.MetricsDurationBuckets,
+		SizeBuckets:      cfg.MetricsSizeBuckets,
+		NativeHistograms: cfg.MetricsNativeHistograms,
+		Registerer:       a.registry,
+	})
+
+	// Build interceptor chain
+	interceptors := []grpc.UnaryServerInterceptor{
+		middleware.RequestIDInterceptor(a.logger),
+		middleware.DebugLogInterceptor(a.debugLogToken.Value),         // Force debug logs for trusted callers
+		middleware.AdminInterceptor(a.adminToken.Value),               // Mark admin callers for admin-only options
+		middleware.TraceLogInterceptor(),                              // Add trace_id/span_id to request logs
+		middleware.RecoveryInterceptor(cfg.CrashDumpDir, grpcMetrics), // Recover panics with request ID available for logging
+		middleware.MetricsInterceptor(grpcMetrics),                    // Add metrics collection,
+	}
+	interceptors = append(interceptors, middleware.RateLimitInterceptor(a.rateLimiter, grpcMetrics)) // Limits follow config reloads
+	interceptors = append(interceptors, middleware.LoggingInterceptor(a.logger, middleware.LoggingConfig{
+		MaxPayloadBytes:   cfg.LogPayloadMaxBytes,
+		SuccessSampleRate: cfg.LogSuccessSampleRate,
+		MethodSampleRates: cfg.LogMethodSampleRates,
+	}))
+	interceptors = append(interceptors, middleware.ErrorConversionInterceptor()) // Automatic error conversion
+
+	return grpc.NewServer(
+		grpc.ChainUnaryInterceptor(interceptors...),
+		grpc.ChainStreamInterceptor(middleware.RecoveryStreamInterceptor(cfg.CrashDumpDir, grpcMetrics)),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),                    // Server spans with W3C trace context extraction
+		grpc.StatsHandler(middleware.NewMetricsStatsHandler(grpcMetrics)), // Connection count and message sizes
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     cfg.GRPCMaxConnectionIdle,
+			MaxConnectionAge:      cfg.GRPCMaxConnectionAge,
+			MaxConnectionAgeGrace: cfg.GRPCMaxConnectionAgeGrace,
+			Time:                  cfg.GRPCKeepaliveTime,
+			Timeout:               cfg.GRPCKeepaliveTimeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             cfg.GRPCKeepaliveMinTime,
+			PermitWithoutStream: cfg.GRPCPermitWithoutStream,
+		}),
+		grpc.MaxRecvMsgSize(cfg.GRPCMaxRecvMsgSize),
+		grpc.MaxSendMsgSize(cfg.GRPCMaxSendMsgSize),
+		grpc.MaxConcurrentStreams(uint32(cfg.GRPCMaxConcurrentStreams)),
+	)
+}
+
+// HTTPHandler builds the HTTP gateway: every module's gateway routes proxied to the gRPC
+// server at endpoint, health, Swagger docs and the HTTP middleware chain. Extra dial
+// options are appended to the defaults; the gateway connections close when ctx is done.
+func (a *App) HTTPHandler(ctx context.Context, endpoint string, dialOpts ...grpc.DialOption) (http.Handler, error) {
+	cfg := a.cfg
+	mux := runtime.NewServeMux(
+		runtime.WithMetadata(middleware.GatewayRequestIDMetadata),       // Forward X-Request-ID to gRPC metadata
+		runtime.WithMiddlewares(middleware.GatewayRouteMiddleware),      // Expose matched route for metrics and logs
+		runtime.WithForwardResponseOption(middleware.GatewayETagHeader), // ETag header from the response's etag field
+		runtime.WithErrorHandler(middleware.GatewayErrorHandler),        // 412 for failed If-Match preconditions
+	)
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()), // Propagate traceparent to the gRPC server
+	}
+	opts = append(opts, dialOpts...)
+
+	for _, module := range a.modules {
+		if err := module.RegisterGateway(ctx, mux, endpoint, opts); err != nil {
+			return nil, fmt.Errorf("failed to register gateway for module %s: %w", module.Name(), err)
+		}
+	}
+
+	// Create a new HTTP mux for additional endpoints
+	httpMux := http.NewServeMux()
+
+	// Register gRPC gateway, answering matching If-None-Match with 304
+	httpMux.Handle("/", middleware.HTTPConditionalGetMiddleware()(mux))
+
+	// Register health endpoint
+	httpMux.Handle("/health", a.healthChecker)
+
+	// Register Swagger endpoints
+	httpMux.HandleFunc("/docs", handlers.SwaggerUIHandler())
+	httpMux.HandleFunc("/docs/swagger.json", handlers.SwaggerHandler())
+
+	// Register Prometheus metrics and log level endpoints here unless a separate admin port serves them
+	if cfg.AdminPort == "" {
+		httpMux.Handle("/metrics", metrics.Handler(a.registry))
+
+		// Register runtime log level endpoint (GET to read, PUT {"level":"debug"} to change),
+		// which requires the admin token like on the admin port
+		if cfg.LogLevelEndpointEnabled {
+			httpMux.Handle("/admin/log/level", middleware.AdminAuthMiddleware(a.adminToken.Value)(logger.LevelHandler()))
+		}
+	}
+
+	// HTTP middleware chain: request ID, RED metrics and access logs
+	resolveRoute := middleware.ServeMuxRouteResolver(httpMux)
+	handler := middleware.ChainHTTP(httpMux,
+		middleware.HTTPRequestIDMiddleware(a.logger),
+		middleware.HTTPMetricsMiddleware(a.httpMetrics, resolveRoute),
+		middleware.HTTPAccessLogMiddleware(resolveRoute, isProbe),
+	)
+
+	// Trace gateway requests, skipping health checks and metric scrapes
+	handler = otelhttp.NewHandler(handler, "http-gateway",
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return !isProbe(r)
+		}),
+		otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}),
+	)
+	return handler, nil
+}
+
+// isProbe reports whether r is a health check or a metrics scrape, which are not traced
+// and are logged only when they fail
+func isProbe(r *http.Request) bool {
+	return r.URL.Path == "/health" || r.URL.Path == "/metrics"
+}
+
+// AdminHandler serves metrics and the debug endpoints, which require a bearer token or a
+// verified client certificate
+func (a *App) AdminHandler() http.Handler {
+	cfg := a.cfg
+	debugMux := http.NewServeMux()
+
+	// Register pprof endpoints
+	debugMux.HandleFunc("/debug/pprof/", pprof.Index)
+	debugMux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	debugMux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	debugMux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	debugMux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	// Register runtime debug endpoints
+	debugMux.Handle("/debug/vars", expvar.Handler())
+	debugMux.HandleFunc("/debug/goroutines", handlers.GoroutineDumpHandler())
+	debugMux.HandleFunc("/debug/gc", handlers.GCStatsHandler())
+	debugMux.HandleFunc("/debug/config", handlers.ConfigHandler(a.Config))
+
+	// Register runtime log level endpoint (GET to read, PUT {"level":"debug"} to change)
+	if cfg.LogLevelEndpointEnabled {
+		debugMux.Handle("/admin/log/level", logger.LevelHandler())
+	}
+
+	adminAuth := middleware.AdminAuthMiddleware(a.adminToken.Value)
+	adminMux := http.NewServeMux()
+	adminMux.Handle("/debug/", adminAuth(debugMux))
+	adminMux.Handle("/admin/", adminAuth(debugMux))
+
+	// Register Prometheus metrics endpoint, left open for scrapers
+	adminMux.Handle("/metrics", metrics.Handler(a.registry))
+
+	return middleware.ChainHTTP(adminMux, middleware.HTTPRequestIDMiddleware(a.logger))
+}
+
+// newAdminServer creates the admin server, with TLS when a certificate is configured
+func (a *App) newAdminServer() (*http.Server, error) {
+	cfg := a.cfg
+	if a.adminToken.Value() == "" && cfg.AdminClientCAFile == "" {
+		a.logger.Warn("Admin authentication not configured, debug endpoints will reject all requests")
+	}
+
+	server := &http.Server{Addr: cfg.AdminPort, Handler: a.AdminHandler()}
+	if cfg.AdminTLSCertFile != "" {
+		tlsConfig, err := adminTLSConfig(cfg.AdminClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		server.TLSConfig = tlsConfig
+	}
+	return server, nil
+}
+
+// serveAdmin listens on the admin port until the server is shut down
+func (a *App) serveAdmin(server *http.Server) error {
+	cfg := a.cfg
+	if server.TLSConfig == nil {
+		a.logger.Info("Admin server listening", zap.String("port", cfg.AdminPort))
+		return server.ListenAndServe()
+	}
+
+	a.logger.Info("Admin server listening with TLS",
+		zap.String("port", cfg.AdminPort),
+		zap.Bool("client_cert_auth", cfg.AdminClientCAFile != ""),
+	)
+	return server.ListenAndServeTLS(cfg.AdminTLSCertFile, cfg.AdminTLSKeyFile)
+}
+
+// adminTLSConfig verifies client certificates against clientCAFile when one is set.
+// Certificates are optional so that token authentication keeps working over TLS.
+func adminTLSConfig(clientCAFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if clientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	caPEM, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read admin client CA: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in admin client CA file %s", clientCAFile)
+	}
+
+	tlsConfig.ClientCAs = clientCAs
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	return tlsConfig, nil
+}
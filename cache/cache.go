@@ -0,0 +1,64 @@
+// Package cache provides byte caches with per-entry TTLs behind the Backend interface: an
+// in-process LRU and a client for Redis-compatible servers, along with the hit and miss
+// metrics of the caches built on them.
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Backend stores values under string keys until their TTL expires. Implementations are
+// safe for concurrent use.
+type Backend interface {
+	// Get returns the value of key; found is false when it is missing or expired
+	Get(ctx context.Context, key string) (value []byte, found bool, err error)
+	// Set stores value under key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys, ignoring missing ones
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Result is the outcome of a cache lookup
+type Result string
+
+// Lookup results
+const (
+	Hit         Result = "hit"          // the value was cached
+	NegativeHit Result = "negative_hit" // the absence of the value was cached
+	Miss        Result = "miss"         // nothing was cached; the value is loaded
+	Error       Result = "error"        // the backend failed; the value is loaded
+)
+
+// Metrics counts cache lookups by cache name and result
+type Metrics struct {
+	lookups *prometheus.CounterVec
+}
+
+// NewMetrics creates the cache lookup metrics and registers them with reg, which defaults
+// to prometheus.DefaultRegisterer
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	return &Metrics{
+		lookups: promauto.With(reg).NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "cache_lookups_total",
+				Help: "Total number of cache lookups by cache and result (hit, negative_hit, miss, error)",
+			},
+			[]string{"cache", "result"},
+		),
+	}
+}
+
+// Record counts a lookup of the named cache; nil metrics record nothing
+func (m *Metrics) Record(name string, result Result) {
+	if m == nil {
+		return
+	}
+	m.lookups.WithLabelValues(name, string(result)).Inc()
+}
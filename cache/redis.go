@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Backend storing values on a Redis-compatible server (Redis, Valkey,
+// KeyDB, ...) with GET, SET and DEL, so entries are shared by every instance of the
+// application. It opens at most poolSize connections; commands wait for a free one.
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis creates a client for the server at rawURL, redis://[user:password@]host:port[/db]
+// or rediss:// for TLS. Connections are opened on first use.
+func NewRedis(rawURL string, poolSize int) (*Redis, error) {
+	options, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+
+	// RESP2 without CLIENT SETINFO, which older compatible servers lack; AUTH and SELECT
+	// replace HELLO when the server rejects it
+	options.Protocol = 2
+	options.DisableIdentity = true
+	options.ContextTimeoutEnabled = true
+	options.PoolSize = poolSize
+	options.MaxActiveConns = poolSize
+	options.MaxIdleConns = poolSize
+	return &Redis{client: redis.NewClient(options)}, nil
+}
+
+// Get implements Backend
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set implements Backend
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, key, value, max(ttl, time.Millisecond)).Err()
+}
+
+// Delete implements Backend
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return r.client.Del(ctx, keys...).Err()
+}
+
+// Ping checks that the server answers
+func (r *Redis) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// Close closes the connections of the client
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
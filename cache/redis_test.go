@@ -0,0 +1,252 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a RESP server answering commands with reply, which returns the raw reply
+type fakeRedis struct {
+	listener net.Listener
+	reply    func(args []string) string
+
+	mu       sync.Mutex
+	commands []string
+	conns    atomic.Int32
+	active   atomic.Int32
+	peak     atomic.Int32
+}
+
+func newFakeRedis(t *testing.T, reply func(args []string) string) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeRedis{listener: listener, reply: reply}
+	t.Cleanup(func() { _ = listener.Close() })
+	go server.serve()
+	return server
+}
+
+func (s *fakeRedis) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.conns.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		args[0] = strings.ToLower(args[0])
+		s.mu.Lock()
+		s.commands = append(s.commands, strings.Join(args, " "))
+		s.mu.Unlock()
+
+		active := s.active.Add(1)
+		for peak := s.peak.Load(); active > peak && !s.peak.CompareAndSwap(peak, active); peak = s.peak.Load() {
+		}
+		reply := s.reply(args)
+		s.active.Add(-1)
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// received returns the commands received so far, lowercased, with their arguments
+func (s *fakeRedis) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// readCommand reads an array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+// storeReply answers like a server holding a single key "present" set to "value"
+func storeReply(args []string) string {
+	switch args[0] {
+	case "get":
+		if args[1] == "present" {
+			return "$5\r\nvalue\r\n"
+		}
+		return "$-1\r\n"
+	case "set", "auth", "select":
+		return "+OK\r\n"
+	case "del":
+		return fmt.Sprintf(":%d\r\n", len(args)-1)
+	case "ping":
+		return "+PONG\r\n"
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+func newTestRedis(t *testing.T, rawURL string, poolSize int) *Redis {
+	t.Helper()
+	client, err := NewRedis(rawURL, poolSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestRedisGetSetDelete(t *testing.T) {
+	server := newFakeRedis(t, storeReply)
+	client := newTestRedis(t, "redis://"+server.listener.Addr().String(), 2)
+	ctx := context.Background()
+
+	value, found, err := client.Get(ctx, "present")
+	if err != nil || !found || string(value) != "value" {
+		t.Errorf("Get(present) = %q, %v, %v, want value, true, nil", value, found, err)
+	}
+	if value, found, err := client.Get(ctx, "missing"); err != nil || found {
+		t.Errorf("Get(missing) = %q, %v, %v, want a miss", value, found, err)
+	}
+	if err := client.Set(ctx, "key", []byte("v"), 1500*time.Millisecond); err != nil {
+		t.Errorf("Set() = %v", err)
+	}
+	if err := client.Delete(ctx, "a", "b"); err != nil {
+		t.Errorf("Delete() = %v", err)
+	}
+
+	want := []string{"hello 2", "get present", "get missing", "set key v px 1500", "del a b"}
+	if got := server.received(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+	if conns := server.conns.Load(); conns != 1 {
+		t.Errorf("connections = %d, want the connection reused by every command", conns)
+	}
+}
+
+func TestRedisErrorReply(t *testing.T) {
+	server := newFakeRedis(t, func(args []string) string {
+		if args[0] == "get" && args[1] == "wrong" {
+			return "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+		}
+		return storeReply(args)
+	})
+	client := newTestRedis(t, "redis://"+server.listener.Addr().String(), 1)
+	ctx := context.Background()
+
+	if _, _, err := client.Get(ctx, "wrong"); err == nil || !strings.Contains(err.Error(), "WRONGTYPE") {
+		t.Errorf("Get(wrong) error = %v, want the WRONGTYPE reply", err)
+	}
+	if _, found, err := client.Get(ctx, "present"); err != nil || !found {
+		t.Errorf("Get(present) after an error reply = %v, %v, want a hit", found, err)
+	}
+	if conns := server.conns.Load(); conns != 1 {
+		t.Errorf("connections = %d, want the connection kept after an error reply", conns)
+	}
+}
+
+func TestRedisAuthSelect(t *testing.T) {
+	server := newFakeRedis(t, storeReply)
+	client := newTestRedis(t, "redis://cache:secret@"+server.listener.Addr().String()+"/3", 1)
+
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() = %v", err)
+	}
+	// the server lacks HELLO, so the client falls back to AUTH and SELECT
+	want := []string{"hello 2 auth cache secret", "auth cache secret", "select 3", "ping"}
+	if got := server.received(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestRedisPoolSizeCapsConnections(t *testing.T) {
+	server := newFakeRedis(t, func(args []string) string {
+		time.Sleep(10 * time.Millisecond)
+		return storeReply(args)
+	})
+	client := newTestRedis(t, "redis://"+server.listener.Addr().String(), 2)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := client.Get(context.Background(), "present"); err != nil {
+				t.Errorf("Get() = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if conns := server.conns.Load(); conns > 2 {
+		t.Errorf("connections = %d, want at most the pool size 2", conns)
+	}
+	if peak := server.peak.Load(); peak > 2 {
+		t.Errorf("concurrent commands = %d, want at most the pool size 2", peak)
+	}
+}
+
+func TestRedisContextCanceled(t *testing.T) {
+	release := make(chan struct{})
+	server := newFakeRedis(t, func(args []string) string {
+		if args[1] == "slow" {
+			<-release
+		}
+		return storeReply(args)
+	})
+	defer close(release)
+	client := newTestRedis(t, "redis://"+server.listener.Addr().String(), 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, _, err := client.Get(ctx, "slow"); err == nil {
+		t.Error("Get(slow) = nil error, want the context deadline error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Get(slow) returned after %v, want it bounded by the context", elapsed)
+	}
+	if _, found, err := client.Get(context.Background(), "present"); err != nil || !found {
+		t.Errorf("Get(present) after a timed out command = %v, %v, want a hit on a fresh connection", found, err)
+	}
+}
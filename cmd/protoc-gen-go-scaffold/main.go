@@ -0,0 +1,138 @@
+// protoc-gen-go-scaffold generates the layers of a new entity following the User patterns:
+// repository interface and MySQL implementation, usecase with tests, service, error codes,
+// app module and SQL migration stubs.
+//
+// Services opt in with the scaffold.service option, naming the gorm ormable message they
+// manage and the first free error code number:
+//
+//	service OrderService {
+//	  option (scaffold.service) = {entity: "OrderEntity", error_code_start: 23};
+//	  rpc CreateOrder(CreateOrderRequest) returns (CreateOrderResponse);
+//	  ...
+//	}
+//
+// Create<Entity>, Get<Entity>, Update<Entity> and Delete<Entity> RPCs are implemented; other
+// RPCs are left to the embedded Unimplemented server. Files are written relative to the
+// project root and existing files are never overwritten, so scaffolded code can be edited
+// freely. Parameters:
+//
+//	base=<module>     Go module path of the project (defaults to the path before /gen/)
+//	root=<dir>        directory the output paths are checked against (defaults to .)
+//	overwrite=true    regenerate files that already exist
+package main
+
+import (
+	"bytes"
+	"embed"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// output maps a template to the file it produces
+type output struct {
+	template string
+	path     func(e *entityData) string
+}
+
+var outputs = []output{
+	{"repository.go.tmpl", func(e *entityData) string { return "repository/" + e.Snake + "_repository.go" }},
+	{"repository_mysql.go.tmpl", func(e *entityData) string { return "repository/" + e.Snake + "_repository_mysql.go" }},
+	{"usecase.go.tmpl", func(e *entityData) string { return "usecase/" + e.Snake + "_usecase.go" }},
+	{"usecase_test.go.tmpl", func(e *entityData) string { return "usecase/" + e.Snake + "_usecase_test.go" }},
+	{"service.go.tmpl", func(e *entityData) string { return "service/" + e.Snake + "_service.go" }},
+	{"codes.go.tmpl", func(e *entityData) string { return "error/" + e.Snake + "_codes.go" }},
+	{"module.go.tmpl", func(e *entityData) string { return "modules/" + e.Snake + ".go" }},
+	{"migration.up.sql.tmpl", func(e *entityData) string { return "migrations/create_" + e.Table + ".up.sql" }},
+	{"migration.down.sql.tmpl", func(e *entityData) string { return "migrations/create_" + e.Table + ".down.sql" }},
+}
+
+func main() {
+	var flags flag.FlagSet
+	base := flags.String("base", "", "Go module path of the project")
+	root := flags.String("root", ".", "directory the output paths are checked against")
+	overwrite := flags.Bool("overwrite", false, "regenerate files that already exist")
+
+	templates := template.Must(template.New("scaffold").Funcs(template.FuncMap{
+		"add":     func(a, b int) int { return a + b },
+		"code":    func(status, start, offset int) string { return fmt.Sprintf("ERR%dP%02d", status, start+offset) },
+		"zapType": zapType,
+		"snake":   func(name string) string { return strings.Join(splitWords(name), "_") },
+	}).ParseFS(templateFS, "templates/*.tmpl"))
+
+	protogen.Options{ParamFunc: flags.Set}.Run(func(plugin *protogen.Plugin) error {
+		plugin.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+
+		for _, file := range plugin.Files {
+			if !file.Generate {
+				continue
+			}
+			for _, service := range file.Services {
+				opts, ok := serviceOptions(service)
+				if !ok {
+					continue
+				}
+				module := *base
+				if module == "" {
+					module, _, _ = strings.Cut(string(file.GoImportPath), "/gen/")
+				}
+				data, err := newEntityData(module, file, service, opts)
+				if err != nil {
+					return err
+				}
+				if err := generate(plugin, templates, data, *root, *overwrite); err != nil {
+					return fmt.Errorf("%s: %w", service.Desc.Name(), err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// generate renders every output of an entity, skipping files that already exist
+func generate(plugin *protogen.Plugin, templates *template.Template, data *entityData, root string, overwrite bool) error {
+	for _, out := range outputs {
+		path := out.path(data)
+		if !overwrite {
+			if _, err := os.Stat(filepath.Join(root, path)); err == nil {
+				continue
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := templates.ExecuteTemplate(&buf, out.template, data); err != nil {
+			return err
+		}
+		content := buf.Bytes()
+		if strings.HasSuffix(path, ".go") {
+			formatted, err := format.Source(content)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			content = formatted
+		}
+
+		if _, err := plugin.NewGeneratedFile(path, "").Write(content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zapType returns the zap field constructor for a Go integer type, e.g. Uint32
+func zapType(goType string) string {
+	if goType == "" {
+		return "Any"
+	}
+	return strings.ToUpper(goType[:1]) + goType[1:]
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+	"unicode"
+
+	scaffoldpb "github.com/harryosmar/protobuf-go/gen/scaffold"
+	gorm "github.com/infobloxopen/protoc-gen-gorm/options"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Method kinds recognized by name: <Kind><Entity>, e.g. CreateOrder
+const (
+	kindCreate = "Create"
+	kindGet    = "Get"
+	kindUpdate = "Update"
+	kindDelete = "Delete"
+)
+
+// entityData is the template input for one scaffolded service
+type entityData struct {
+	Module       string // Go module path of the project
+	Source       string // proto file the service is declared in
+	Name         string // Order
+	Var          string // order
+	Snake        string // order_item
+	Label        string // order item
+	Table        string
+	PBPkg        string // orderpb
+	PBImport     string
+	Message      string // OrderEntity
+	Service      string // OrderService
+	ID           fieldData
+	Fields       []fieldData
+	UniqueFields []fieldData // string columns with a unique index, looked up by GetBy<Field>
+	CodeStart    int
+	Methods      []methodData
+	Skipped      []string // RPCs left to the embedded Unimplemented server
+}
+
+// fieldData describes a column of the entity
+type fieldData struct {
+	GoName string
+	Var    string
+	GoType string
+	Column string
+	SQL    string // column definition for the migration stub
+	Unique string // unique index name, if any
+}
+
+// methodData describes a recognized RPC of the service
+type methodData struct {
+	Name     string
+	Kind     string
+	Request  string
+	Response string
+	Input    string   // request field carrying the entity data for create/update
+	IsEntity bool     // the input field is the entity message itself
+	Copy     []string // fields copied from the input message to the entity
+	IDField  string   // request field holding the entity ID
+	IDType   string   // Go type of IDField
+	Output   string   // response field receiving the entity
+}
+
+// serviceOptions returns the scaffold options of a service, if it opted in
+func serviceOptions(service *protogen.Service) (*scaffoldpb.ServiceOptions, bool) {
+	opts := service.Desc.Options()
+	if opts == nil || !proto.HasExtension(opts, scaffoldpb.E_Service) {
+		return nil, false
+	}
+	return proto.GetExtension(opts, scaffoldpb.E_Service).(*scaffoldpb.ServiceOptions), true
+}
+
+// newEntityData collects everything the templates need about service and its entity
+func newEntityData(module string, file *protogen.File, service *protogen.Service, opts *scaffoldpb.ServiceOptions) (*entityData, error) {
+	message, err := findEntity(file, opts.GetEntity())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", service.Desc.Name(), err)
+	}
+	if opts.GetErrorCodeStart() <= 0 {
+		return nil, fmt.Errorf("%s: error_code_start is required", service.Desc.Name())
+	}
+
+	name := strings.TrimSuffix(message.GoIdent.GoName, "Entity")
+	words := splitWords(name)
+	data := &entityData{
+		Module:    module,
+		Source:    file.Desc.Path(),
+		Name:      name,
+		Var:       goVar(lowerFirst(name)),
+		Snake:     strings.Join(words, "_"),
+		Label:     strings.Join(words, " "),
+		Table:     messageOptions(message).GetTable(),
+		PBPkg:     string(file.GoPackageName) + "pb",
+		PBImport:  string(file.GoImportPath),
+		Message:   message.GoIdent.GoName,
+		Service:   service.GoName,
+		CodeStart: int(opts.GetErrorCodeStart()),
+	}
+	if data.Table == "" {
+		data.Table = data.Snake + "s"
+	}
+
+	hasID := false
+	for _, field := range message.Fields {
+		tag := fieldTag(field)
+		if tag.GetIgnore() {
+			continue
+		}
+		column := newFieldData(field, tag)
+		data.Fields = append(data.Fields, column)
+		if tag.GetPrimaryKey() || (!hasID && field.Desc.Name() == "id") {
+			data.ID = column
+			hasID = true
+		}
+		if column.Unique != "" && field.Desc.Kind() == protoreflect.StringKind {
+			data.UniqueFields = append(data.UniqueFields, column)
+		}
+	}
+	if !hasID {
+		return nil, fmt.Errorf("%s has no primary key field", message.Desc.Name())
+	}
+
+	for _, method := range service.Methods {
+		if m, ok := newMethodData(method, message, name); ok {
+			data.Methods = append(data.Methods, m)
+			continue
+		}
+		data.Skipped = append(data.Skipped, method.GoName)
+	}
+	return data, nil
+}
+
+// findEntity returns the named ormable message, or the only one in file when name is empty
+func findEntity(file *protogen.File, name string) (*protogen.Message, error) {
+	var ormable []*protogen.Message
+	for _, message := range file.Messages {
+		if !messageOptions(message).GetOrmable() {
+			continue
+		}
+		if name != "" && string(message.Desc.Name()) == name {
+			return message, nil
+		}
+		ormable = append(ormable, message)
+	}
+	if name != "" {
+		return nil, fmt.Errorf("ormable message %s not found in %s", name, file.Desc.Path())
+	}
+	if len(ormable) != 1 {
+		return nil, fmt.Errorf("%s has %d ormable messages, set the entity option", file.Desc.Path(), len(ormable))
+	}
+	return ormable[0], nil
+}
+
+// newMethodData recognizes Create, Get, Update and Delete RPCs of the entity
+func newMethodData(method *protogen.Method, entity *protogen.Message, name string) (methodData, bool) {
+	kind, ok := strings.CutSuffix(method.GoName, name)
+	if !ok {
+		return methodData{}, false
+	}
+	m := methodData{
+		Name:     method.GoName,
+		Kind:     kind,
+		Request:  method.Input.GoIdent.GoName,
+		Response: method.Output.GoIdent.GoName,
+	}
+
+	for _, field := range method.Input.Fields {
+		switch {
+		case field.Desc.Name() == "id" && isInteger(field.Desc.Kind()):
+			m.IDField = field.GoName
+			m.IDType = goType(field)
+		case field.Message != nil && m.Input == "" && !field.Desc.IsList() && !field.Desc.IsMap():
+			m.Input = field.GoName
+			m.IsEntity = field.Message == entity
+			if !m.IsEntity {
+				m.Copy = commonFields(field.Message, entity)
+			}
+		}
+	}
+	for _, field := range method.Output.Fields {
+		if field.Message == entity {
+			m.Output = field.GoName
+			break
+		}
+	}
+
+	switch kind {
+	case kindCreate:
+		return m, m.Input != ""
+	case kindUpdate:
+		return m, m.Input != "" && (m.IsEntity || m.IDField != "")
+	case kindGet, kindDelete:
+		return m, m.IDField != ""
+	}
+	return methodData{}, false
+}
+
+// commonFields lists the fields of from that the entity has with the same name and type
+func commonFields(from, entity *protogen.Message) []string {
+	var names []string
+	for _, field := range from.Fields {
+		for _, target := range entity.Fields {
+			if field.GoName == target.GoName && sameType(field, target) && target.Desc.Name() != "id" {
+				names = append(names, field.GoName)
+			}
+		}
+	}
+	return names
+}
+
+// sameType reports whether two fields hold values of the same Go type
+func sameType(a, b *protogen.Field) bool {
+	if a.Desc.Kind() != b.Desc.Kind() || a.Desc.Cardinality() != b.Desc.Cardinality() {
+		return false
+	}
+	if a.Message != nil || b.Message != nil {
+		return a.Message != nil && b.Message != nil && a.Message.Desc.FullName() == b.Message.Desc.FullName()
+	}
+	if a.Enum != nil || b.Enum != nil {
+		return a.Enum != nil && b.Enum != nil && a.Enum.Desc.FullName() == b.Enum.Desc.FullName()
+	}
+	return true
+}
+
+// newFieldData maps a proto field and its gorm tag to a column
+func newFieldData(field *protogen.Field, tag *gorm.GormTag) fieldData {
+	column := tag.GetColumn()
+	if column == "" {
+		column = strings.Join(splitWords(field.GoName), "_")
+	}
+	unique := tag.GetUniqueIndex()
+	if unique == "" && tag.GetUnique() {
+		unique = column + "_unique"
+	}
+
+	data := fieldData{
+		GoName: field.GoName,
+		Var:    goVar(lowerFirst(field.GoName)),
+		GoType: goType(field),
+		Column: column,
+		Unique: unique,
+	}
+
+	sqlType := tag.GetType()
+	if sqlType == "" {
+		sqlType = sqlColumnType(field, tag)
+	}
+	parts := []string{"`" + column + "`", sqlType}
+	if tag.GetNotNull() || tag.GetPrimaryKey() {
+		parts = append(parts, "NOT NULL")
+	}
+	if tag.GetAutoIncrement() {
+		parts = append(parts, "AUTO_INCREMENT")
+	}
+	if tag.GetDefault() != "" {
+		parts = append(parts, "DEFAULT "+tag.GetDefault())
+	}
+	data.SQL = strings.Join(parts, " ")
+	return data
+}
+
+// messageOptions returns the gorm options of a message
+func messageOptions(message *protogen.Message) *gorm.GormMessageOptions {
+	opts := message.Desc.Options()
+	if opts == nil || !proto.HasExtension(opts, gorm.E_Opts) {
+		return nil
+	}
+	return proto.GetExtension(opts, gorm.E_Opts).(*gorm.GormMessageOptions)
+}
+
+// fieldTag returns the gorm tag of a field
+func fieldTag(field *protogen.Field) *gorm.GormTag {
+	opts := field.Desc.Options()
+	if opts == nil || !proto.HasExtension(opts, gorm.E_Field) {
+		return nil
+	}
+	return proto.GetExtension(opts, gorm.E_Field).(*gorm.GormFieldOptions).GetTag()
+}
+
+// goType returns the Go type of a scalar field; it is only used for IDs and lookups
+func goType(field *protogen.Field) string {
+	switch field.Desc.Kind() {
+	case protoreflect.BoolKind:
+		return "bool"
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return "int32"
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return "uint32"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return "int64"
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "uint64"
+	case protoreflect.FloatKind:
+		return "float32"
+	case protoreflect.DoubleKind:
+		return "float64"
+	case protoreflect.StringKind:
+		return "string"
+	case protoreflect.BytesKind:
+		return "[]byte"
+	}
+	return "any"
+}
+
+// sqlColumnType follows the MySQL types GORM picks for the generated ORM fields
+func sqlColumnType(field *protogen.Field, tag *gorm.GormTag) string {
+	switch field.Desc.Kind() {
+	case protoreflect.BoolKind:
+		return "BOOLEAN"
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind, protoreflect.EnumKind:
+		return "INT"
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return "INT UNSIGNED"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return "BIGINT"
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "BIGINT UNSIGNED"
+	case protoreflect.FloatKind:
+		return "FLOAT"
+	case protoreflect.DoubleKind:
+		return "DOUBLE"
+	case protoreflect.StringKind:
+		if tag.GetSize() > 0 {
+			return fmt.Sprintf("VARCHAR(%d)", tag.GetSize())
+		}
+		return "LONGTEXT"
+	case protoreflect.BytesKind:
+		return "LONGBLOB"
+	case protoreflect.MessageKind:
+		if field.Message.Desc.FullName() == "google.protobuf.Timestamp" {
+			return "DATETIME(3)"
+		}
+	}
+	return "TEXT /* TODO: check the column type */"
+}
+
+// isInteger reports whether kind is an integer kind usable as an ID
+func isInteger(kind protoreflect.Kind) bool {
+	switch kind {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return true
+	}
+	return false
+}
+
+// splitWords splits a CamelCase name into lowercase words, keeping acronyms together
+func splitWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		upper := unicode.IsUpper(runes[i])
+		boundary := upper && (!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])))
+		if boundary || runes[i] == '_' {
+			words = append(words, strings.ToLower(strings.Trim(string(runes[start:i]), "_")))
+			start = i
+		}
+	}
+	words = append(words, strings.ToLower(strings.Trim(string(runes[start:]), "_")))
+	return words
+}
+
+// lowerFirst lowercases the first letter, or the whole leading acronym
+func lowerFirst(name string) string {
+	words := splitWords(name)
+	if len(words) == 0 {
+		return name
+	}
+	first := len(words[0])
+	return words[0] + name[first:]
+}
+
+// goVar avoids identifiers that are Go keywords
+func goVar(name string) string {
+	if token.IsKeyword(name) {
+		return name + "Value"
+	}
+	return name
+}
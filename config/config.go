@@ -1,51 +1,115 @@
 package config
 
 import (
-	"github.com/kelseyhightower/envconfig"
+	"time"
 )
 
+// Config holds the server configuration. Each field is read, in increasing order of
+// precedence, from its default tag, the config file (key is the lowercased env name),
+// the environment variable named by the envconfig tag and the matching command-line
+// flag (lowercased env name with dashes, e.g. --grpc-port). Fields tagged reload:"true"
+// take effect on a configuration reload; other changes need a restart.
 type Config struct {
+	// Configuration reload (SIGHUP always reloads)
+	ConfigWatchInterval time.Duration `envconfig:"CONFIG_WATCH_INTERVAL" default:"5s"` // how often the config file is checked for changes, 0 disables
+
 	// Application settings
 	AppName    string `envconfig:"APP_NAME" default:"protobuf-go-server"`
 	AppVersion string `envconfig:"APP_VERSION" default:"v1.0.0"`
 
+	// Error handling configuration
+	ErrorStackTraceEnabled bool   `envconfig:"ERROR_STACK_TRACE_ENABLED" default:"false"`
+	CrashDumpDir           string `envconfig:"CRASH_DUMP_DIR" default:""` // empty disables crash report files
+
+	// Logging configuration
+	LogLevel                string             `envconfig:"LOG_LEVEL" default:"info" reload:"true"`           // debug, info, warn, error
+	LogRedactStrategy       string             `envconfig:"LOG_REDACT_STRATEGY" default:"mask" reload:"true"` // mask, hash, drop
+	LogPayloadMaxBytes      int                `envconfig:"LOG_PAYLOAD_MAX_BYTES" default:"1024"`
+	LogSuccessSampleRate    float64            `envconfig:"LOG_SUCCESS_SAMPLE_RATE" default:"1"`             // 0-1, failures are always logged
+	LogMethodSampleRates    map[string]float64 `envconfig:"LOG_METHOD_SAMPLE_RATES"`                         // e.g. /hello.HelloService/GetHello:0.01
+	LogDebugHeaderToken     string             `envconfig:"LOG_DEBUG_HEADER_TOKEN" default:"" secret:"true"` // x-debug-log value that forces debug logs, empty disables
+	LogLevelEndpointEnabled bool               `envconfig:"LOG_LEVEL_ENDPOINT_ENABLED" default:"false"`
+
+	// Tracing configuration (OTLP endpoint via standard OTEL_EXPORTER_OTLP_* variables)
+	TracingEnabled     bool    `envconfig:"TRACING_ENABLED" default:"false"`
+	TracingExporter    string  `envconfig:"TRACING_EXPORTER" default:"otlp"` // otlp, stdout, file
+	TracingFilePath    string  `envconfig:"TRACING_FILE_PATH" default:"traces.json"`
+	TracingSampleRatio float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1"`
+
+	// Metrics configuration
+	MetricsDurationBuckets  []float64 `envconfig:"METRICS_DURATION_BUCKETS"` // seconds, comma separated
+	MetricsSizeBuckets      []float64 `envconfig:"METRICS_SIZE_BUCKETS"`     // bytes, comma separated
+	MetricsNativeHistograms bool      `envconfig:"METRICS_NATIVE_HISTOGRAMS" default:"false"`
+
 	// Server ports
-	GRPCPort string `envconfig:"GRPC_PORT" default:":50051"`
-	HTTPPort string `envconfig:"HTTP_PORT" default:":8080"`
-
-	// Database configuration
-	DatabaseURL            string `envconfig:"DATABASE_URL" default:"root:password@tcp(localhost:3306)/protobuf_go?charset=utf8mb4&parseTime=True&loc=Local"`
-	DatabaseMaxIdle        int    `envconfig:"DATABASE_MAX_IDLE" default:"10"`
-	DatabaseMaxOpen        int    `envconfig:"DATABASE_MAX_OPEN" default:"100"`
-	DatabaseMaxLife        int    `envconfig:"DATABASE_MAX_LIFE" default:"3600"` // seconds
-	DatabaseMaxRetries     int    `envconfig:"DATABASE_MAX_RETRIES" default:"3"`
-	DatabaseRetryDelay     int    `envconfig:"DATABASE_RETRY_DELAY" default:"1"`      // seconds
-	DatabaseConnectTimeout int    `envconfig:"DATABASE_CONNECT_TIMEOUT" default:"10"` // seconds
-	DatabaseQueryTimeout   int    `envconfig:"DATABASE_QUERY_TIMEOUT" default:"30"`   // seconds
+	GRPCPort  string `envconfig:"GRPC_PORT" default:":50051"`
+	HTTPPort  string `envconfig:"HTTP_PORT" default:":8080"`
+	AdminPort string `envconfig:"ADMIN_PORT" default:""` // serves /metrics, pprof and debug endpoints; empty keeps /metrics on HTTP_PORT
+
+	// Admin server authentication for /debug and /admin endpoints (bearer token or verified client certificate)
+	AdminToken        string `envconfig:"ADMIN_TOKEN" default:"" secret:"true"`
+	AdminTLSCertFile  string `envconfig:"ADMIN_TLS_CERT_FILE" default:""` // enables TLS on ADMIN_PORT together with ADMIN_TLS_KEY_FILE
+	AdminTLSKeyFile   string `envconfig:"ADMIN_TLS_KEY_FILE" default:""`
+	AdminClientCAFile string `envconfig:"ADMIN_CLIENT_CA_FILE" default:""` // client certificates signed by this CA are authorized
+
+	// Secret references (file:///path, env://NAME, vault://path#key) accepted by DATABASE_URL, ADMIN_TOKEN, LOG_DEBUG_HEADER_TOKEN and USER_CACHE_REDIS_URL
+	SecretsVaultFile       string        `envconfig:"SECRETS_VAULT_FILE" default:""`         // JSON file standing in for Vault, enables vault:// references
+	SecretsRefreshInterval time.Duration `envconfig:"SECRETS_REFRESH_INTERVAL" default:"1m"` // how often DATABASE_URL and the tokens are re-resolved to pick up rotated values, 0 disables
+
+	// Storage: memory keeps users in process, needs no database and loses them on exit; for tests and local development
+	StorageBackend string `envconfig:"STORAGE_BACKEND" default:"mysql"` // mysql, memory
+
+	// Database configuration (STORAGE_BACKEND=mysql)
+	DatabaseURL                string        `envconfig:"DATABASE_URL" default:"root:password@tcp(localhost:3306)/protobuf_go?charset=utf8mb4&parseTime=True&loc=Local" secret:"dsn"`
+	DatabaseMaxIdle            int           `envconfig:"DATABASE_MAX_IDLE" default:"10"`
+	DatabaseMaxOpen            int           `envconfig:"DATABASE_MAX_OPEN" default:"100"`
+	DatabaseMaxLife            time.Duration `envconfig:"DATABASE_MAX_LIFE" default:"1h"` // 0 keeps connections forever
+	DatabaseMaxRetries         int           `envconfig:"DATABASE_MAX_RETRIES" default:"3"`
+	DatabaseRetryDelay         time.Duration `envconfig:"DATABASE_RETRY_DELAY" default:"1s"` // doubled after every failed attempt
+	DatabaseConnectTimeout     time.Duration `envconfig:"DATABASE_CONNECT_TIMEOUT" default:"10s"`
+	DatabaseQueryTimeout       time.Duration `envconfig:"DATABASE_QUERY_TIMEOUT" default:"30s"`
+	DatabaseSlowQueryThreshold time.Duration `envconfig:"DATABASE_SLOW_QUERY_THRESHOLD" default:"200ms"` // slower queries are logged as warnings
+	DatabaseLogLevel           string        `envconfig:"DATABASE_LOG_LEVEL" default:"warn"`             // silent, error, warn, info (info logs every statement)
+
+	// User retention: deleted users are purged permanently once older than the retention
+	UserPurgeRetention time.Duration `envconfig:"USER_PURGE_RETENTION" default:"720h"` // how long deleted users can be restored
+	UserPurgeInterval  time.Duration `envconfig:"USER_PURGE_INTERVAL" default:"1h"`    // how often the purge job runs, 0 disables
+	UserPurgeBatchSize int           `envconfig:"USER_PURGE_BATCH_SIZE" default:"500"` // rows deleted per statement
+
+	// User cache: user lookups by ID and email are served from the cache until entries expire
+	UserCacheBackend       string        `envconfig:"USER_CACHE_BACKEND" default:"none"`                                    // none, memory, redis
+	UserCacheSize          int           `envconfig:"USER_CACHE_SIZE" default:"10000"`                                      // entries kept by the memory backend
+	UserCacheTTL           time.Duration `envconfig:"USER_CACHE_TTL" default:"5m"`                                          // how long users are cached
+	UserCacheNegativeTTL   time.Duration `envconfig:"USER_CACHE_NEGATIVE_TTL" default:"30s"`                                // how long unknown users are cached, 0 disables
+	UserCacheKeyPrefix     string        `envconfig:"USER_CACHE_KEY_PREFIX" default:"users:"`                               // namespaces keys on a shared Redis
+	UserCacheRedisURL      string        `envconfig:"USER_CACHE_REDIS_URL" default:"redis://localhost:6379/0" secret:"url"` // redis:// or rediss://, with optional user:password@
+	UserCacheRedisPoolSize int           `envconfig:"USER_CACHE_REDIS_POOL_SIZE" default:"10"`                              // maximum open connections
 
 	// Rate limiting configuration
-	RateLimitEnabled        bool   `envconfig:"RATE_LIMIT_ENABLED" default:"true"`
-	RateLimitRequestsPerSec int    `envconfig:"RATE_LIMIT_REQUESTS_PER_SEC" default:"100"`
-	RateLimitBurstSize      int    `envconfig:"RATE_LIMIT_BURST_SIZE" default:"200"`
-	RateLimitStrategy       string `envconfig:"RATE_LIMIT_STRATEGY" default:"global"` // global, per-method
+	RateLimitEnabled        bool   `envconfig:"RATE_LIMIT_ENABLED" default:"true" reload:"true"`
+	RateLimitRequestsPerSec int    `envconfig:"RATE_LIMIT_REQUESTS_PER_SEC" default:"100" reload:"true"`
+	RateLimitBurstSize      int    `envconfig:"RATE_LIMIT_BURST_SIZE" default:"200" reload:"true"`
+	RateLimitStrategy       string `envconfig:"RATE_LIMIT_STRATEGY" default:"global" reload:"true"` // global, per-method
 
 	// gRPC server configuration
-	GRPCMaxConnectionIdle     int  `envconfig:"GRPC_MAX_CONNECTION_IDLE" default:"15"`     // seconds
-	GRPCMaxConnectionAge      int  `envconfig:"GRPC_MAX_CONNECTION_AGE" default:"30"`      // seconds
-	GRPCMaxConnectionAgeGrace int  `envconfig:"GRPC_MAX_CONNECTION_AGE_GRACE" default:"5"` // seconds
-	GRPCKeepaliveTime         int  `envconfig:"GRPC_KEEPALIVE_TIME" default:"5"`           // seconds
-	GRPCKeepaliveTimeout      int  `envconfig:"GRPC_KEEPALIVE_TIMEOUT" default:"1"`        // seconds
-	GRPCKeepaliveMinTime      int  `envconfig:"GRPC_KEEPALIVE_MIN_TIME" default:"5"`       // seconds
-	GRPCPermitWithoutStream   bool `envconfig:"GRPC_PERMIT_WITHOUT_STREAM" default:"false"`
-	GRPCMaxRecvMsgSize        int  `envconfig:"GRPC_MAX_RECV_MSG_SIZE" default:"4194304"` // 4MB in bytes
-	GRPCMaxSendMsgSize        int  `envconfig:"GRPC_MAX_SEND_MSG_SIZE" default:"4194304"` // 4MB in bytes
-	GRPCMaxConcurrentStreams  int  `envconfig:"GRPC_MAX_CONCURRENT_STREAMS" default:"1000"`
+	GRPCMaxConnectionIdle     time.Duration `envconfig:"GRPC_MAX_CONNECTION_IDLE" default:"15s"`
+	GRPCMaxConnectionAge      time.Duration `envconfig:"GRPC_MAX_CONNECTION_AGE" default:"30s"`
+	GRPCMaxConnectionAgeGrace time.Duration `envconfig:"GRPC_MAX_CONNECTION_AGE_GRACE" default:"5s"`
+	GRPCKeepaliveTime         time.Duration `envconfig:"GRPC_KEEPALIVE_TIME" default:"5s"`
+	GRPCKeepaliveTimeout      time.Duration `envconfig:"GRPC_KEEPALIVE_TIMEOUT" default:"1s"`
+	GRPCKeepaliveMinTime      time.Duration `envconfig:"GRPC_KEEPALIVE_MIN_TIME" default:"5s"`
+	GRPCPermitWithoutStream   bool          `envconfig:"GRPC_PERMIT_WITHOUT_STREAM" default:"false"`
+	GRPCMaxRecvMsgSize        int           `envconfig:"GRPC_MAX_RECV_MSG_SIZE" default:"4194304"` // 4MB in bytes
+	GRPCMaxSendMsgSize        int           `envconfig:"GRPC_MAX_SEND_MSG_SIZE" default:"4194304"` // 4MB in bytes
+	GRPCMaxConcurrentStreams  int           `envconfig:"GRPC_MAX_CONCURRENT_STREAMS" default:"1000"`
 }
 
-// Get loads configuration from environment variables
+// Get loads configuration from defaults, CONFIG_FILE and environment variables.
+// It panics on invalid configuration; use Load to handle errors.
 func Get() *Config {
-	cfg := Config{}
-	envconfig.MustProcess("", &cfg)
-
-	return &cfg
+	cfg, err := Load(nil)
+	if err != nil {
+		panic(err)
+	}
+	return cfg
 }
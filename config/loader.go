@@ -0,0 +1,334 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the environment variable holding the config file path; --config overrides it
+const ConfigFileEnv = "CONFIG_FILE"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// field describes how a Config field is named in each configuration source
+type field struct {
+	index      int
+	env        string // environment variable, e.g. GRPC_PORT
+	key        string // config file key, e.g. grpc_port
+	flag       string // command-line flag, e.g. grpc-port
+	def        string
+	hasDefault bool
+}
+
+// fields lists every Config field that has an envconfig tag, in declaration order
+func fields() []field {
+	configType := reflect.TypeOf(Config{})
+	result := make([]field, 0, configType.NumField())
+	for i := 0; i < configType.NumField(); i++ {
+		tag := configType.Field(i).Tag
+		env := tag.Get("envconfig")
+		if env == "" {
+			continue
+		}
+		def, hasDefault := tag.Lookup("default")
+		key := strings.ToLower(env)
+		result = append(result, field{
+			index:      i,
+			env:        env,
+			key:        key,
+			flag:       strings.ReplaceAll(key, "_", "-"),
+			def:        def,
+			hasDefault: hasDefault,
+		})
+	}
+	return result
+}
+
+// Load builds the configuration from defaults, the YAML or TOML file named by --config or
+// CONFIG_FILE, environment variables and the command-line flags in args, then validates it.
+// Every invalid value is reported in the returned error, not only the first one.
+func Load(args []string) (*Config, error) {
+	all := fields()
+
+	// Flags are parsed first to find the config file but are applied last
+	flagValues, configFile, err := parseFlags(all, args)
+	if err != nil {
+		return nil, err
+	}
+	if configFile == "" {
+		configFile = os.Getenv(ConfigFileEnv)
+	}
+
+	cfg := &Config{}
+	var errs []error
+
+	for _, f := range all {
+		if f.hasDefault {
+			errs = append(errs, f.set(cfg, f.def, "default"))
+		}
+	}
+
+	if configFile != "" {
+		errs = append(errs, loadFile(cfg, all, configFile)...)
+	}
+
+	for _, f := range all {
+		if value, ok := os.LookupEnv(f.env); ok {
+			errs = append(errs, f.set(cfg, value, "env"))
+		}
+	}
+
+	for _, f := range all {
+		if value, ok := flagValues[f.flag]; ok {
+			errs = append(errs, f.set(cfg, value, "flag"))
+		}
+	}
+
+	// Fields that failed to parse keep their previous value, so validation still reports the rest
+	errs = append(errs, cfg.Validate())
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parseFlags collects the raw values of the flags set in args and the --config path
+func parseFlags(all []field, args []string) (map[string]string, string, error) {
+	flagSet := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := flagSet.String("config", "", "path to a YAML or TOML config file (overrides "+ConfigFileEnv+")")
+
+	values := make(map[string]string)
+	configType := reflect.TypeOf(Config{})
+	for _, f := range all {
+		name := f.flag
+		usage := "overrides " + f.env
+		if f.hasDefault && f.def != "" {
+			usage += " (default " + f.def + ")"
+		}
+		record := func(value string) error {
+			values[name] = value
+			return nil
+		}
+		if configType.Field(f.index).Type.Kind() == reflect.Bool {
+			flagSet.BoolFunc(name, usage, record)
+		} else {
+			flagSet.Func(name, usage, record)
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return nil, "", err
+	}
+	if flagSet.NArg() > 0 {
+		return nil, "", fmt.Errorf("unexpected arguments: %s", strings.Join(flagSet.Args(), " "))
+	}
+	return values, *configFile, nil
+}
+
+// loadFile applies the values of a YAML or TOML config file, rejecting unknown keys
+func loadFile(cfg *Config, all []field, path string) []error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("failed to read config file: %w", err)}
+	}
+
+	values := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		_, err = toml.Decode(string(data), &values)
+	default:
+		err = fmt.Errorf("unsupported format %q, use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return []error{fmt.Errorf("failed to parse config file %s: %w", path, err)}
+	}
+
+	byKey := make(map[string]field, len(all))
+	for _, f := range all {
+		byKey[f.key] = f
+	}
+
+	// Sort keys so errors are reported in a stable order
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		f, ok := byKey[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("config file %s: unknown key %q", path, key))
+			continue
+		}
+		raw, err := fileValueString(values[key])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: %s: %w", path, key, err))
+			continue
+		}
+		errs = append(errs, f.set(cfg, raw, "config file"))
+	}
+	return errs
+}
+
+// fileValueString converts a decoded file value to the string form used by env vars and flags
+func fileValueString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			raw, err := fileValueString(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, raw)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]interface{}:
+		pairs := make([]string, 0, len(v))
+		for key, item := range v {
+			raw, err := fileValueString(item)
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, key+":"+raw)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// set parses raw into the field, naming the source in the error
+func (f field) set(cfg *Config, raw, source string) error {
+	target := reflect.ValueOf(cfg).Elem().Field(f.index)
+	if err := setValue(target, raw); err != nil {
+		return fmt.Errorf("%s: invalid value %q from %s: %w", f.env, raw, source, err)
+	}
+	return nil
+}
+
+// setValue parses raw according to the type of target. Lists are comma separated and
+// maps use key:value pairs, e.g. "0.1,0.5,1" and "/pkg.Service/Method:0.01".
+func setValue(target reflect.Value, raw string) error {
+	if target.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		target.SetInt(int64(d))
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		target.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		target.SetInt(int64(n))
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		target.SetFloat(n)
+	case reflect.Slice:
+		list := reflect.MakeSlice(target.Type(), 0, 0)
+		for _, item := range splitList(raw) {
+			elem := reflect.New(target.Type().Elem()).Elem()
+			if err := setValue(elem, item); err != nil {
+				return err
+			}
+			list = reflect.Append(list, elem)
+		}
+		target.Set(list)
+	case reflect.Map:
+		entries := reflect.MakeMap(target.Type())
+		for _, pair := range splitList(raw) {
+			key, value, ok := strings.Cut(pair, ":")
+			if !ok {
+				return fmt.Errorf("invalid map entry %q, expected key:value", pair)
+			}
+			elem := reflect.New(target.Type().Elem()).Elem()
+			if err := setValue(elem, strings.TrimSpace(value)); err != nil {
+				return err
+			}
+			entries.SetMapIndex(reflect.ValueOf(strings.TrimSpace(key)), elem)
+		}
+		target.Set(entries)
+	default:
+		return fmt.Errorf("unsupported field type %s", target.Type())
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// WriteYAML writes the configuration as a YAML config file, using file keys in declaration order
+func (c Config) WriteYAML(w io.Writer) error {
+	document := &yaml.Node{Kind: yaml.MappingNode}
+	value := reflect.ValueOf(c)
+
+	for _, f := range fields() {
+		fieldValue := value.Field(f.index).Interface()
+		if d, ok := fieldValue.(time.Duration); ok {
+			fieldValue = d.String()
+		}
+
+		valueNode := &yaml.Node{}
+		if err := valueNode.Encode(fieldValue); err != nil {
+			return fmt.Errorf("%s: %w", f.key, err)
+		}
+		document.Content = append(document.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: f.key},
+			valueNode,
+		)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
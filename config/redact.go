@@ -0,0 +1,61 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/harryosmar/protobuf-go/secrets"
+)
+
+// RedactedValue replaces secret values in redacted output
+const RedactedValue = "REDACTED"
+
+// Redacted returns a copy of the configuration that is safe to log or expose.
+// Fields tagged secret:"true" are replaced with RedactedValue when set, and fields
+// tagged secret:"dsn" (MySQL DSNs) or secret:"url" keep everything except the password. Secret references such as
+// file:///run/secrets/db hold no secret themselves and are kept as they are.
+func (c Config) Redacted() Config {
+	value := reflect.ValueOf(&c).Elem()
+	fields := value.Type()
+
+	for i := 0; i < fields.NumField(); i++ {
+		field := value.Field(i)
+		if field.Kind() != reflect.String || field.String() == "" || secrets.IsReference(field.String()) {
+			continue
+		}
+		switch fields.Field(i).Tag.Get("secret") {
+		case "true":
+			field.SetString(RedactedValue)
+		case "dsn":
+			field.SetString(RedactDSN(field.String()))
+		case "url":
+			field.SetString(RedactURL(field.String()))
+		}
+	}
+	return c
+}
+
+// RedactDSN masks the password of a MySQL DSN; DSNs that cannot be parsed are fully redacted
+func RedactDSN(dsn string) string {
+	parsed, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return RedactedValue
+	}
+	if parsed.Passwd != "" {
+		parsed.Passwd = RedactedValue
+	}
+	return parsed.FormatDSN()
+}
+
+// RedactURL masks the password of a URL; URLs that cannot be parsed are fully redacted
+func RedactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return RedactedValue
+	}
+	if _, ok := parsed.User.Password(); ok {
+		parsed.User = url.UserPassword(parsed.User.Username(), RedactedValue)
+	}
+	return parsed.String()
+}
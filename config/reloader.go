@@ -0,0 +1,244 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// Reload results recorded by config_reloads_total
+const (
+	ReloadSuccess  = "success"
+	ReloadInvalid  = "invalid"
+	ReloadReverted = "reverted"
+)
+
+// SubscriberFunc applies a configuration snapshot. Returning an error reverts the reload.
+type SubscriberFunc func(cfg *Config) error
+
+// Change describes one field that differs between two configurations; secrets are redacted
+type Change struct {
+	Key        string `json:"key"`
+	Old        string `json:"old"`
+	New        string `json:"new"`
+	Reloadable bool   `json:"reloadable"` // false when the change only takes effect after a restart
+}
+
+// Reloader loads new configuration snapshots and publishes them to subscribers
+type Reloader struct {
+	args        []string
+	logger      *zap.Logger
+	current     atomic.Pointer[Config]
+	subscribers []subscriber
+	mutex       sync.Mutex // serializes reloads and subscriber registration
+	reloads     *prometheus.CounterVec
+	lastSuccess prometheus.Gauge
+}
+
+type subscriber struct {
+	name  string
+	apply SubscriberFunc
+}
+
+// NewReloader creates a reloader that re-reads the sources given by args, starting from
+// initial. Its reload metrics are registered with reg, or left unregistered when reg is nil.
+func NewReloader(args []string, initial *Config, logger *zap.Logger, reg prometheus.Registerer) *Reloader {
+	factory := promauto.With(reg)
+	reloader := &Reloader{
+		args:   args,
+		logger: logger,
+		reloads: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "config_reloads_total",
+				Help: "Total number of configuration reloads by result",
+			},
+			[]string{"result"},
+		),
+		lastSuccess: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "config_last_reload_success_timestamp_seconds",
+				Help: "Timestamp of the last successful configuration reload",
+			},
+		),
+	}
+	reloader.current.Store(initial)
+	return reloader
+}
+
+// Current returns the latest applied configuration snapshot
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// Subscribe registers fn to receive every new snapshot, in registration order
+func (r *Reloader) Subscribe(name string, fn SubscriberFunc) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.subscribers = append(r.subscribers, subscriber{name: name, apply: fn})
+}
+
+// Reload loads and validates the configuration, then applies its fields tagged
+// reload:"true" to every subscriber; other changes are ignored until a restart.
+// Invalid configuration is rejected; if a subscriber fails, subscribers that already
+// applied the new snapshot are reverted to the current one.
+func (r *Reloader) Reload(trigger string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	current := r.current.Load()
+	next, err := Load(r.args)
+	if err != nil {
+		r.reloads.WithLabelValues(ReloadInvalid).Inc()
+		r.logger.Error("Configuration reload rejected, keeping current configuration",
+			zap.String("trigger", trigger),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	changes := Diff(current, next)
+	if len(changes) == 0 {
+		r.reloads.WithLabelValues(ReloadSuccess).Inc()
+		r.lastSuccess.SetToCurrentTime()
+		r.logger.Info("Configuration reloaded without changes", zap.String("trigger", trigger))
+		return nil
+	}
+
+	// Settings that need a restart keep their running values, so Current keeps describing
+	// what the process actually uses
+	applied := withReloadable(current, next)
+	for i, sub := range r.subscribers {
+		if err := sub.apply(applied); err != nil {
+			r.revert(current, i)
+			r.reloads.WithLabelValues(ReloadReverted).Inc()
+			r.logger.Error("Configuration reload failed, reverted to current configuration",
+				zap.String("trigger", trigger),
+				zap.String("subscriber", sub.name),
+				zap.Any("changes", changes),
+				zap.Error(err),
+			)
+			return fmt.Errorf("subscriber %s: %w", sub.name, err)
+		}
+	}
+
+	r.current.Store(applied)
+	r.reloads.WithLabelValues(ReloadSuccess).Inc()
+	r.lastSuccess.SetToCurrentTime()
+	r.logger.Info("Configuration reloaded",
+		zap.String("trigger", trigger),
+		zap.Any("changes", changes),
+	)
+
+	var restartRequired []string
+	for _, change := range changes {
+		if !change.Reloadable {
+			restartRequired = append(restartRequired, change.Key)
+		}
+	}
+	if len(restartRequired) > 0 {
+		r.logger.Warn("Configuration changes ignored until restart",
+			zap.Strings("keys", restartRequired),
+		)
+	}
+	return nil
+}
+
+// withReloadable returns a copy of current with the fields tagged reload:"true" taken from next
+func withReloadable(current, next *Config) *Config {
+	applied := *current
+	appliedValue, nextValue := reflect.ValueOf(&applied).Elem(), reflect.ValueOf(next).Elem()
+	configType := appliedValue.Type()
+	for i := 0; i < configType.NumField(); i++ {
+		if configType.Field(i).Tag.Get("reload") == "true" {
+			appliedValue.Field(i).Set(nextValue.Field(i))
+		}
+	}
+	return &applied
+}
+
+// revert re-applies previous to the subscribers up to and including the one at failed
+func (r *Reloader) revert(previous *Config, failed int) {
+	for i := failed; i >= 0; i-- {
+		sub := r.subscribers[i]
+		if err := sub.apply(previous); err != nil {
+			r.logger.Error("Failed to revert configuration subscriber",
+				zap.String("subscriber", sub.name),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// Watch reloads the configuration whenever the config file changes, checking every interval,
+// until ctx is done. It returns immediately when no config file is used or interval is not positive.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	path := ResolveFile(r.args)
+	if path == "" || interval <= 0 {
+		return
+	}
+
+	lastModTime, lastSize := fileVersion(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			modTime, size := fileVersion(path)
+			if modTime.Equal(lastModTime) && size == lastSize {
+				continue
+			}
+			lastModTime, lastSize = modTime, size
+			r.Reload("file")
+		}
+	}
+}
+
+// fileVersion returns the modification time and size of path, zero values when it is missing
+func fileVersion(path string) (time.Time, int64) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, 0
+	}
+	return info.ModTime(), info.Size()
+}
+
+// ResolveFile returns the config file named by --config in args or by CONFIG_FILE
+func ResolveFile(args []string) string {
+	if _, configFile, err := parseFlags(fields(), args); err == nil && configFile != "" {
+		return configFile
+	}
+	return os.Getenv(ConfigFileEnv)
+}
+
+// Diff lists the fields that differ between old and new, using config file keys.
+// Secret values are redacted, so a changed secret shows the same redacted value on both sides.
+func Diff(old, new *Config) []Change {
+	oldValue, newValue := reflect.ValueOf(*old), reflect.ValueOf(*new)
+	oldRedacted, newRedacted := reflect.ValueOf(old.Redacted()), reflect.ValueOf(new.Redacted())
+	configType := oldValue.Type()
+
+	var changes []Change
+	for _, f := range fields() {
+		if reflect.DeepEqual(oldValue.Field(f.index).Interface(), newValue.Field(f.index).Interface()) {
+			continue
+		}
+		changes = append(changes, Change{
+			Key:        f.key,
+			Old:        fmt.Sprint(oldRedacted.Field(f.index).Interface()),
+			New:        fmt.Sprint(newRedacted.Field(f.index).Interface()),
+			Reloadable: configType.Field(f.index).Tag.Get("reload") == "true",
+		})
+	}
+	return changes
+}
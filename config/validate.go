@@ -0,0 +1,229 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/harryosmar/protobuf-go/cache"
+	"github.com/harryosmar/protobuf-go/secrets"
+)
+
+var (
+	logLevels           = []string{"debug", "info", "warn", "error"}
+	logRedactStrategies = []string{"mask", "hash", "drop"}
+	tracingExporters    = []string{"otlp", "stdout", "file"}
+	storageBackends     = []string{"mysql", "memory"}
+	databaseLogLevels   = []string{"silent", "error", "warn", "info"}
+	rateLimitStrategies = []string{"global", "per-method"}
+	userCacheBackends   = []string{"none", "memory", "redis"}
+)
+
+// Validate checks the configuration and reports every invalid field at once
+func (c *Config) Validate() error {
+	var errs []error
+
+	errs = append(errs, validateNonNegativeDuration("CONFIG_WATCH_INTERVAL", c.ConfigWatchInterval))
+
+	// Server ports
+	errs = append(errs,
+		validateAddress("GRPC_PORT", c.GRPCPort, true),
+		validateAddress("HTTP_PORT", c.HTTPPort, true),
+		validateAddress("ADMIN_PORT", c.AdminPort, false),
+	)
+	if c.AdminTLSCertFile != "" && c.AdminTLSKeyFile == "" || c.AdminTLSCertFile == "" && c.AdminTLSKeyFile != "" {
+		errs = append(errs, errors.New("ADMIN_TLS_CERT_FILE and ADMIN_TLS_KEY_FILE must be set together"))
+	}
+	if c.AdminClientCAFile != "" && c.AdminTLSCertFile == "" {
+		errs = append(errs, errors.New("ADMIN_CLIENT_CA_FILE requires ADMIN_TLS_CERT_FILE and ADMIN_TLS_KEY_FILE"))
+	}
+
+	// Logging
+	errs = append(errs,
+		validateOneOf("LOG_LEVEL", c.LogLevel, logLevels),
+		validateOneOf("LOG_REDACT_STRATEGY", c.LogRedactStrategy, logRedactStrategies),
+		validatePositive("LOG_PAYLOAD_MAX_BYTES", c.LogPayloadMaxBytes),
+		validateRatio("LOG_SUCCESS_SAMPLE_RATE", c.LogSuccessSampleRate),
+	)
+	for _, method := range slices.Sorted(maps.Keys(c.LogMethodSampleRates)) {
+		errs = append(errs, validateRatio("LOG_METHOD_SAMPLE_RATES["+method+"]", c.LogMethodSampleRates[method]))
+	}
+
+	// Tracing
+	errs = append(errs,
+		validateOneOf("TRACING_EXPORTER", c.TracingExporter, tracingExporters),
+		validateRatio("TRACING_SAMPLE_RATIO", c.TracingSampleRatio),
+	)
+
+	// Metrics
+	errs = append(errs,
+		validateBuckets("METRICS_DURATION_BUCKETS", c.MetricsDurationBuckets),
+		validateBuckets("METRICS_SIZE_BUCKETS", c.MetricsSizeBuckets),
+	)
+
+	// Secrets
+	errs = append(errs, validateNonNegativeDuration("SECRETS_REFRESH_INTERVAL", c.SecretsRefreshInterval))
+	for _, secret := range []struct{ name, value string }{
+		{"DATABASE_URL", c.DatabaseURL},
+		{"ADMIN_TOKEN", c.AdminToken},
+		{"LOG_DEBUG_HEADER_TOKEN", c.LogDebugHeaderToken},
+		{"USER_CACHE_REDIS_URL", c.UserCacheRedisURL},
+	} {
+		if strings.HasPrefix(secret.value, secrets.SchemeVault+"://") && c.SecretsVaultFile == "" {
+			errs = append(errs, fmt.Errorf("%s: vault:// references require SECRETS_VAULT_FILE", secret.name))
+		}
+	}
+
+	// Storage
+	errs = append(errs, validateOneOf("STORAGE_BACKEND", c.StorageBackend, storageBackends))
+
+	// Database (a secret reference is checked when it is resolved)
+	if !secrets.IsReference(c.DatabaseURL) {
+		if _, err := mysql.ParseDSN(c.DatabaseURL); err != nil {
+			errs = append(errs, fmt.Errorf("DATABASE_URL: invalid DSN: %w", err))
+		}
+	}
+	errs = append(errs,
+		validateNonNegative("DATABASE_MAX_IDLE", c.DatabaseMaxIdle),
+		validatePositive("DATABASE_MAX_OPEN", c.DatabaseMaxOpen),
+		validatePositive("DATABASE_MAX_RETRIES", c.DatabaseMaxRetries),
+		validateNonNegativeDuration("DATABASE_MAX_LIFE", c.DatabaseMaxLife),
+		validatePositiveDuration("DATABASE_RETRY_DELAY", c.DatabaseRetryDelay),
+		validatePositiveDuration("DATABASE_CONNECT_TIMEOUT", c.DatabaseConnectTimeout),
+		validatePositiveDuration("DATABASE_QUERY_TIMEOUT", c.DatabaseQueryTimeout),
+		validatePositiveDuration("DATABASE_SLOW_QUERY_THRESHOLD", c.DatabaseSlowQueryThreshold),
+		validateOneOf("DATABASE_LOG_LEVEL", c.DatabaseLogLevel, databaseLogLevels),
+	)
+	if c.DatabaseMaxIdle > c.DatabaseMaxOpen {
+		errs = append(errs, fmt.Errorf("DATABASE_MAX_IDLE (%d) must not exceed DATABASE_MAX_OPEN (%d)", c.DatabaseMaxIdle, c.DatabaseMaxOpen))
+	}
+
+	// User retention
+	errs = append(errs,
+		validatePositiveDuration("USER_PURGE_RETENTION", c.UserPurgeRetention),
+		validateNonNegativeDuration("USER_PURGE_INTERVAL", c.UserPurgeInterval),
+		validatePositive("USER_PURGE_BATCH_SIZE", c.UserPurgeBatchSize),
+	)
+
+	// User cache (a secret reference is checked when it is resolved)
+	errs = append(errs,
+		validateOneOf("USER_CACHE_BACKEND", c.UserCacheBackend, userCacheBackends),
+		validatePositive("USER_CACHE_SIZE", c.UserCacheSize),
+		validatePositiveDuration("USER_CACHE_TTL", c.UserCacheTTL),
+		validateNonNegativeDuration("USER_CACHE_NEGATIVE_TTL", c.UserCacheNegativeTTL),
+		validatePositive("USER_CACHE_REDIS_POOL_SIZE", c.UserCacheRedisPoolSize),
+	)
+	if c.UserCacheBackend == "redis" && !secrets.IsReference(c.UserCacheRedisURL) {
+		if _, err := cache.NewRedis(c.UserCacheRedisURL, c.UserCacheRedisPoolSize); err != nil {
+			errs = append(errs, fmt.Errorf("USER_CACHE_REDIS_URL: %w", err))
+		}
+	}
+
+	// Rate limiting
+	errs = append(errs,
+		validatePositive("RATE_LIMIT_REQUESTS_PER_SEC", c.RateLimitRequestsPerSec),
+		validatePositive("RATE_LIMIT_BURST_SIZE", c.RateLimitBurstSize),
+		validateOneOf("RATE_LIMIT_STRATEGY", c.RateLimitStrategy, rateLimitStrategies),
+	)
+
+	// gRPC server
+	errs = append(errs,
+		validatePositiveDuration("GRPC_MAX_CONNECTION_IDLE", c.GRPCMaxConnectionIdle),
+		validatePositiveDuration("GRPC_MAX_CONNECTION_AGE", c.GRPCMaxConnectionAge),
+		validateNonNegativeDuration("GRPC_MAX_CONNECTION_AGE_GRACE", c.GRPCMaxConnectionAgeGrace),
+		validatePositiveDuration("GRPC_KEEPALIVE_TIME", c.GRPCKeepaliveTime),
+		validatePositiveDuration("GRPC_KEEPALIVE_TIMEOUT", c.GRPCKeepaliveTimeout),
+		validatePositiveDuration("GRPC_KEEPALIVE_MIN_TIME", c.GRPCKeepaliveMinTime),
+		validatePositive("GRPC_MAX_RECV_MSG_SIZE", c.GRPCMaxRecvMsgSize),
+		validatePositive("GRPC_MAX_SEND_MSG_SIZE", c.GRPCMaxSendMsgSize),
+		validatePositive("GRPC_MAX_CONCURRENT_STREAMS", c.GRPCMaxConcurrentStreams),
+	)
+
+	return errors.Join(errs...)
+}
+
+// validateAddress checks a listen address such as ":8080" or "0.0.0.0:8080"
+func validateAddress(name, address string, required bool) error {
+	if address == "" {
+		if required {
+			return fmt.Errorf("%s: must be set", name)
+		}
+		return nil
+	}
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%s: invalid address %q: %w", name, address, err)
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("%s: invalid port %q in %q", name, port, address)
+	}
+	return nil
+}
+
+// validateOneOf checks that value is one of allowed
+func validateOneOf(name, value string, allowed []string) error {
+	if !slices.Contains(allowed, value) {
+		return fmt.Errorf("%s: %q is not one of %v", name, value, allowed)
+	}
+	return nil
+}
+
+// validatePositive checks that value is greater than zero
+func validatePositive(name string, value int) error {
+	if value <= 0 {
+		return fmt.Errorf("%s: must be positive, got %d", name, value)
+	}
+	return nil
+}
+
+// validateNonNegative checks that value is zero or greater
+func validateNonNegative(name string, value int) error {
+	if value < 0 {
+		return fmt.Errorf("%s: must not be negative, got %d", name, value)
+	}
+	return nil
+}
+
+// validatePositiveDuration checks that value is greater than zero
+func validatePositiveDuration(name string, value time.Duration) error {
+	if value <= 0 {
+		return fmt.Errorf("%s: must be positive, got %s", name, value)
+	}
+	return nil
+}
+
+// validateNonNegativeDuration checks that value is zero or greater
+func validateNonNegativeDuration(name string, value time.Duration) error {
+	if value < 0 {
+		return fmt.Errorf("%s: must not be negative, got %s", name, value)
+	}
+	return nil
+}
+
+// validateRatio checks that value is between 0 and 1
+func validateRatio(name string, value float64) error {
+	if value < 0 || value > 1 {
+		return fmt.Errorf("%s: must be between 0 and 1, got %g", name, value)
+	}
+	return nil
+}
+
+// validateBuckets checks that histogram buckets are positive and strictly increasing
+func validateBuckets(name string, buckets []float64) error {
+	for i, bucket := range buckets {
+		if bucket <= 0 {
+			return fmt.Errorf("%s: buckets must be positive, got %g", name, bucket)
+		}
+		if i > 0 && bucket <= buckets[i-1] {
+			return fmt.Errorf("%s: buckets must be strictly increasing, got %v", name, buckets)
+		}
+	}
+	return nil
+}
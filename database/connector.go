@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/harryosmar/protobuf-go/secrets"
+	"go.uber.org/zap"
+)
+
+// mysqlAccessDenied is the MySQL error number for rejected credentials
+const mysqlAccessDenied = 1045
+
+// secretConnector opens MySQL connections with the DSN currently held by a secret, so
+// connections opened after a rotation use the new credentials without a restart
+type secretConnector struct {
+	dsn    *secrets.Secret
+	logger *zap.Logger
+	ctx    context.Context // cancelled when the sql.DB is closed, stops the rotation watcher
+	cancel context.CancelFunc
+}
+
+// newSecretConnector creates a connector for dsn
+func newSecretConnector(dsn *secrets.Secret, logger *zap.Logger) *secretConnector {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &secretConnector{dsn: dsn, logger: logger, ctx: ctx, cancel: cancel}
+}
+
+// Connect implements driver.Connector. When MySQL rejects the credentials, the secret is
+// re-resolved and the connection retried once in case it rotated since the last refresh.
+func (c *secretConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connect(ctx)
+
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlAccessDenied {
+		rotated, refreshErr := c.dsn.Refresh(ctx)
+		if refreshErr == nil && rotated {
+			c.logger.Info("Database credentials rotated, retrying connection",
+				zap.String("secret", c.dsn.Reference()),
+			)
+			return c.connect(ctx)
+		}
+	}
+	return conn, err
+}
+
+// connect opens a connection with the current DSN
+func (c *secretConnector) connect(ctx context.Context) (driver.Conn, error) {
+	// The DSN holds credentials, so parse errors must not echo it
+	mysqlConfig, err := mysqldriver.ParseDSN(c.dsn.Value())
+	if err != nil {
+		return nil, fmt.Errorf("invalid database DSN from %s", c.dsn.Reference())
+	}
+	connector, err := mysqldriver.NewConnector(mysqlConfig)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+// Driver implements driver.Connector
+func (c *secretConnector) Driver() driver.Driver {
+	return &mysqldriver.MySQLDriver{}
+}
+
+// Close is called by sql.DB.Close and stops the rotation watcher
+func (c *secretConnector) Close() error {
+	c.cancel()
+	return nil
+}
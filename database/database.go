@@ -2,11 +2,14 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"math"
 	"time"
 
 	"github.com/harryosmar/protobuf-go/config"
+	"github.com/harryosmar/protobuf-go/secrets"
+	"github.com/harryosmar/protobuf-go/tracing"
 	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -15,28 +18,32 @@ import (
 
 // NewDatabase creates and returns a new database connection with connection pooling and retry logic
 func NewDatabase(cfg *config.Config, zapLogger *zap.Logger) (*gorm.DB, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.DatabaseConnectTimeout)*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.DatabaseConnectTimeout)
 	defer cancel()
 
 	return NewDatabaseWithContext(ctx, cfg, zapLogger)
 }
 
-// NewDatabaseWithContext creates a database connection with context support and retry logic
+// NewDatabaseWithContext creates a database connection with context support and retry logic.
+// Connecting gives up when ctx is done, so ctx should carry DATABASE_CONNECT_TIMEOUT.
 func NewDatabaseWithContext(ctx context.Context, cfg *config.Config, zapLogger *zap.Logger) (*gorm.DB, error) {
-	// Configure GORM logger to use Zap
-	gormLogger := logger.New(
-		&GormZapWriter{logger: zapLogger},
-		logger.Config{
-			SlowThreshold:             time.Second,
-			LogLevel:                  logger.Info,
-			IgnoreRecordNotFoundError: true,
-			Colorful:                  false,
-		},
-	)
+	// Configure GORM logger to use Zap; statements are logged without their values
+	gormLogger := NewGormZapLogger(zapLogger, logger.Config{
+		SlowThreshold:             cfg.DatabaseSlowQueryThreshold,
+		LogLevel:                  gormLogLevel(cfg.DatabaseLogLevel),
+		IgnoreRecordNotFoundError: true,
+		ParameterizedQueries:      true,
+	})
+
+	// DATABASE_URL may be a secret reference such as file:///run/secrets/db; never log its value
+	dsn, err := secrets.NewResolver(cfg.SecretsVaultFile).NewSecret(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve database URL: %w", err)
+	}
 
 	// Open database connection with retry logic
 	var db *gorm.DB
-	var err error
+	var connector *secretConnector
 
 	for attempt := 1; attempt <= cfg.DatabaseMaxRetries; attempt++ {
 		// Check if context is cancelled
@@ -51,28 +58,24 @@ func NewDatabaseWithContext(ctx context.Context, cfg *config.Config, zapLogger *
 			zap.Int("max_retries", cfg.DatabaseMaxRetries),
 		)
 
-		db, err = gorm.Open(mysql.Open(cfg.DatabaseURL), &gorm.Config{
-			Logger: gormLogger,
-		})
-
-		if err == nil {
-			// Connection successful, test it
-			sqlDB, dbErr := db.DB()
-			if dbErr == nil {
-				pingErr := sqlDB.Ping()
-				if pingErr == nil {
-					break // Success
-				}
-				err = pingErr
-			} else {
-				err = dbErr
+		// Each attempt gets its own pool because gorm closes it when initialization fails.
+		// gorm.Open connects without a context, so the pool connects first to honor ctx.
+		connector = newSecretConnector(dsn, zapLogger)
+		pool := sql.OpenDB(connector)
+		if err = pool.PingContext(ctx); err == nil {
+			db, err = gorm.Open(mysql.New(mysql.Config{Conn: pool}), &gorm.Config{
+				Logger: gormLogger,
+			})
+			if err == nil {
+				break // Success
 			}
 		}
+		pool.Close()
 
 		// Connection failed
 		if attempt < cfg.DatabaseMaxRetries {
 			// Calculate exponential backoff delay
-			delay := time.Duration(cfg.DatabaseRetryDelay) * time.Second * time.Duration(math.Pow(2, float64(attempt-1)))
+			delay := cfg.DatabaseRetryDelay * time.Duration(math.Pow(2, float64(attempt-1)))
 			zapLogger.Warn("Database connection failed, retrying",
 				zap.Error(err),
 				zap.Int("attempt", attempt),
@@ -93,6 +96,16 @@ func NewDatabaseWithContext(ctx context.Context, cfg *config.Config, zapLogger *
 		return nil, fmt.Errorf("failed to connect to database after %d attempts: %w", cfg.DatabaseMaxRetries, err)
 	}
 
+	// Emit a span for every query
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		return nil, fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+
+	// Record query duration and errors by operation and table
+	if err := db.Use(NewMetricsPlugin()); err != nil {
+		return nil, fmt.Errorf("failed to register metrics plugin: %w", err)
+	}
+
 	// Get underlying sql.DB to configure connection pool
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -102,29 +115,52 @@ func NewDatabaseWithContext(ctx context.Context, cfg *config.Config, zapLogger *
 	// Configure connection pool for high-traffic
 	sqlDB.SetMaxIdleConns(cfg.DatabaseMaxIdle)
 	sqlDB.SetMaxOpenConns(cfg.DatabaseMaxOpen)
-	sqlDB.SetConnMaxLifetime(time.Duration(cfg.DatabaseMaxLife) * time.Second)
+	sqlDB.SetConnMaxLifetime(cfg.DatabaseMaxLife)
 
 	// Test the connection
-	if err := sqlDB.Ping(); err != nil {
+	if err := sqlDB.PingContext(ctx); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	// Re-resolve the DSN periodically; after a rotation idle connections are dropped so
+	// new ones authenticate with the new credentials
+	go dsn.Watch(connector.ctx, cfg.SecretsRefreshInterval,
+		func() {
+			zapLogger.Info("Database credentials rotated, recycling idle connections",
+				zap.String("secret", dsn.Reference()),
+			)
+			sqlDB.SetMaxIdleConns(0)
+			sqlDB.SetMaxIdleConns(cfg.DatabaseMaxIdle)
+		},
+		func(err error) {
+			zapLogger.Warn("Failed to refresh database credentials, keeping current ones", zap.Error(err))
+		},
+	)
+
 	zapLogger.Info("Database connected successfully",
+		zap.String("secret", dsn.Reference()),
 		zap.String("max_idle", fmt.Sprintf("%d", cfg.DatabaseMaxIdle)),
 		zap.String("max_open", fmt.Sprintf("%d", cfg.DatabaseMaxOpen)),
-		zap.String("max_lifetime", fmt.Sprintf("%ds", cfg.DatabaseMaxLife)),
+		zap.Duration("max_lifetime", cfg.DatabaseMaxLife),
+		zap.Duration("slow_query_threshold", cfg.DatabaseSlowQueryThreshold),
 	)
 
 	return db, nil
 }
 
-// GormZapWriter implements GORM's logger interface using Zap
-type GormZapWriter struct {
-	logger *zap.Logger
-}
-
-func (g *GormZapWriter) Printf(format string, args ...interface{}) {
-	g.logger.Info(fmt.Sprintf(format, args...))
+// gormLogLevel maps the configured level name to a GORM log level, defaulting to warn
+// so that only slow queries and errors are logged
+func gormLogLevel(level string) logger.LogLevel {
+	switch level {
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "info":
+		return logger.Info
+	default:
+		return logger.Warn
+	}
 }
 
 // CloseDatabase closes the database connection
@@ -138,3 +174,12 @@ func CloseDatabase(db *gorm.DB) error {
 	}
 	return nil
 }
+
+// Ping verifies that the database is reachable
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
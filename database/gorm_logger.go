@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// mysqlDuplicateEntry is the MySQL error number for unique key violations
+const mysqlDuplicateEntry = 1062
+
+// GormZapLogger implements GORM's logger interface using Zap, logging failed queries at
+// error, slow queries at warn and, at the info level, every query
+type GormZapLogger struct {
+	logger.Config
+	logger *zap.Logger
+}
+
+// NewGormZapLogger creates a GORM logger writing to zapLogger
+func NewGormZapLogger(zapLogger *zap.Logger, config logger.Config) *GormZapLogger {
+	return &GormZapLogger{Config: config, logger: zapLogger}
+}
+
+// LogMode implements logger.Interface
+func (g *GormZapLogger) LogMode(level logger.LogLevel) logger.Interface {
+	clone := *g
+	clone.LogLevel = level
+	return &clone
+}
+
+// Info implements logger.Interface
+func (g *GormZapLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if g.LogLevel >= logger.Info {
+		g.logger.Info(fmt.Sprintf(msg, args...))
+	}
+}
+
+// Warn implements logger.Interface
+func (g *GormZapLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if g.LogLevel >= logger.Warn {
+		g.logger.Warn(fmt.Sprintf(msg, args...))
+	}
+}
+
+// Error implements logger.Interface
+func (g *GormZapLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if g.LogLevel >= logger.Error {
+		g.logger.Error(fmt.Sprintf(msg, args...))
+	}
+}
+
+// Trace implements logger.Interface by logging the statement of a finished query
+func (g *GormZapLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if g.LogLevel <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	queryFields := func() []zap.Field {
+		sql, rows := fc()
+		return []zap.Field{
+			zap.String("sql", sql),
+			zap.Int64("rows", rows),
+			zap.Duration("duration", elapsed),
+		}
+	}
+
+	switch {
+	case err != nil && g.LogLevel >= logger.Error && !(g.IgnoreRecordNotFoundError && errors.Is(err, gorm.ErrRecordNotFound)):
+		g.logger.Error("Database query failed", append(queryFields(), queryError(err))...)
+	case g.SlowThreshold > 0 && elapsed > g.SlowThreshold && g.LogLevel >= logger.Warn:
+		g.logger.Warn("Slow database query", append(queryFields(), zap.Duration("threshold", g.SlowThreshold))...)
+	case g.LogLevel >= logger.Info:
+		g.logger.Info("Database query", queryFields()...)
+	}
+}
+
+// ParamsFilter implements gorm.ParamsFilter, leaving the values out of logged statements
+// when ParameterizedQueries is set
+func (g *GormZapLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if g.ParameterizedQueries {
+		return sql, nil
+	}
+	return sql, params
+}
+
+// queryError logs err, except for unique key violations whose message quotes the
+// duplicated value, which may be personal data such as an email
+func queryError(err error) zap.Field {
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return zap.Uint16("mysql_error", mysqlErr.Number)
+	}
+	return zap.Error(err)
+}
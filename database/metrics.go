@@ -0,0 +1,152 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+const (
+	metricsStartKey   = "metrics:started_at"
+	metricsPluginName = "prometheus-metrics"
+)
+
+// RegisterMetrics registers the query metrics of db's MetricsPlugin, installing the plugin
+// when db was opened elsewhere, and a connection pool collector with reg. Pool statistics
+// (open, in-use, idle, wait count and wait duration) are exported as go_sql_* metrics
+// labelled with the current database name.
+func RegisterMetrics(reg prometheus.Registerer, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	plugin, ok := db.Config.Plugins[metricsPluginName].(*MetricsPlugin)
+	if !ok {
+		plugin = NewMetricsPlugin()
+		if err := db.Use(plugin); err != nil {
+			return err
+		}
+	}
+
+	dbName := db.Migrator().CurrentDatabase()
+	if dbName == "" {
+		dbName = "unknown"
+	}
+
+	for _, collector := range []prometheus.Collector{
+		plugin.queryDuration,
+		plugin.queryErrors,
+		collectors.NewDBStatsCollector(sqlDB, dbName),
+	} {
+		if err := reg.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MetricsPlugin records query duration and errors by operation and table
+type MetricsPlugin struct {
+	queryDuration *prometheus.HistogramVec
+	queryErrors   *prometheus.CounterVec
+}
+
+// NewMetricsPlugin creates a GORM plugin that records query metrics; RegisterMetrics
+// exports them
+func NewMetricsPlugin() *MetricsPlugin {
+	return &MetricsPlugin{
+		queryDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "db_query_duration_seconds",
+				Help:    "Duration of database queries in seconds",
+				Buckets: []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+			},
+			[]string{"operation", "table"},
+		),
+		queryErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "db_query_errors_total",
+				Help: "Total number of failed database queries",
+			},
+			[]string{"operation", "table"},
+		),
+	}
+}
+
+// Name implements gorm.Plugin
+func (p *MetricsPlugin) Name() string {
+	return metricsPluginName
+}
+
+// Initialize implements gorm.Plugin by registering before/after callbacks around each operation
+func (p *MetricsPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	if err := callbacks.Create().Before("gorm:create").Register("metrics:before_create", p.before); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("gorm:create").Register("metrics:after_create", p.after("insert")); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("metrics:before_query", p.before); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("gorm:query").Register("metrics:after_query", p.after("select")); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("metrics:before_update", p.before); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("metrics:after_update", p.after("update")); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("metrics:before_delete", p.before); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:delete").Register("metrics:after_delete", p.after("delete")); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("metrics:before_row", p.before); err != nil {
+		return err
+	}
+	if err := callbacks.Row().After("gorm:row").Register("metrics:after_row", p.after("row")); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().Before("gorm:raw").Register("metrics:before_raw", p.before); err != nil {
+		return err
+	}
+	return callbacks.Raw().After("gorm:raw").Register("metrics:after_raw", p.after("raw"))
+}
+
+// before stores the start time on the statement
+func (p *MetricsPlugin) before(db *gorm.DB) {
+	db.InstanceSet(metricsStartKey, time.Now())
+}
+
+// after observes the query duration and counts errors other than record not found
+func (p *MetricsPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := "unknown"
+		if db.Statement != nil && db.Statement.Table != "" {
+			table = db.Statement.Table
+		}
+
+		p.queryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			p.queryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -88,6 +89,22 @@ const (
 	ErrUserDeletionFailed
 )
 
+// RegisterCodeErr adds an application error code declared outside this file, such as the
+// codes generated by protoc-gen-go-scaffold. By convention the constant is 100 plus the YY
+// of its ERRXXXPYY code. It must be called from init and panics when the constant or the
+// code is already taken.
+func RegisterCodeErr(c CodeErr, entity CodeErrEntity) {
+	if existing, exists := codeErrMap[c]; exists {
+		panic(fmt.Sprintf("error code constant %d already registered as %s", c, existing.Code))
+	}
+	for _, existing := range codeErrMap {
+		if existing.Code == entity.Code {
+			panic(fmt.Sprintf("error code %s already registered", entity.Code))
+		}
+	}
+	codeErrMap[c] = entity
+}
+
 // Error implements the error interface for CodeErr
 func (c CodeErr) Error() string {
 	if entity, exists := codeErrMap[c]; exists {
@@ -121,59 +138,147 @@ func (c CodeErr) GetMessage() string {
 
 // ToGRPCStatus converts CodeErr to gRPC status
 func (c CodeErr) ToGRPCStatus() error {
-	return status.Error(c.GetCodeErrEntity().GrpcCode, c.GetMessage())
+	return c.ToLocalizedGRPCStatus(DefaultLocale)
 }
 
-// WithMessage returns a formatted error with additional context while preserving CodeErr type
+// ToLocalizedGRPCStatus converts CodeErr to gRPC status with the message translated to locale
+func (c CodeErr) ToLocalizedGRPCStatus(locale string) error {
+	return newGRPCStatus(c.GetCodeErrEntity(), locale, c.LocalizedMessage(locale))
+}
+
+// newGRPCStatus builds a gRPC status carrying the error code and localized message as details
+func newGRPCStatus(entity CodeErrEntity, locale, message string) error {
+	st := status.New(entity.GrpcCode, message)
+	detailed, err := st.WithDetails(
+		&errdetails.ErrorInfo{Reason: entity.Code},
+		&errdetails.LocalizedMessage{Locale: locale, Message: message},
+	)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// WithMessage returns a formatted error with additional context while preserving CodeErr type.
+// The formatted text is not translated; prefer WithTemplate for client-facing details.
 func (c CodeErr) WithMessage(format string, args ...interface{}) *CodeErrWithContext {
-	baseMessage := c.GetMessage()
+	contextErr := newCodeErrWithContext(c)
 	if format != "" {
-		customMessage := fmt.Sprintf(format, args...)
-		return &CodeErrWithContext{
-			CodeErr: c,
-			message: fmt.Sprintf("%s: %s", baseMessage, customMessage),
-		}
-	}
-	return &CodeErrWithContext{
-		CodeErr: c,
-		message: baseMessage,
+		contextErr.detail = fmt.Sprintf(format, args...)
 	}
+	return contextErr
+}
+
+// WithTemplate returns an error whose message is rendered from the catalog entry
+// "<code>.<name>" of the client's locale, substituting {placeholders} from params
+func (c CodeErr) WithTemplate(name string, params Params) *CodeErrWithContext {
+	contextErr := newCodeErrWithContext(c)
+	contextErr.template = c.GetCode() + "." + name
+	contextErr.params = params
+	return contextErr
+}
+
+// Wrap returns an error carrying this code with cause attached as the underlying error.
+// The cause is kept for server-side logging and errors.Is/As, but never sent to clients.
+func (c CodeErr) Wrap(cause error) *CodeErrWithContext {
+	return newCodeErrWithContext(c).Wrap(cause)
 }
 
 // CodeErrWithContext wraps CodeErr with additional context while preserving gRPC compatibility
 type CodeErrWithContext struct {
 	CodeErr
-	message string
+	detail   string
+	template string
+	params   Params
+	cause    error
+	stack    []uintptr
+}
+
+func newCodeErrWithContext(code CodeErr) *CodeErrWithContext {
+	return &CodeErrWithContext{
+		CodeErr: code,
+		stack:   captureStack(),
+	}
 }
 
-// Error implements error interface for CodeErrWithContext
+// Wrap attaches cause as the underlying error and returns the same error for chaining
+func (c *CodeErrWithContext) Wrap(cause error) *CodeErrWithContext {
+	c.cause = cause
+	return c
+}
+
+// Error implements error interface for CodeErrWithContext.
+// The cause is included so that server-side logs show the full chain.
 func (c *CodeErrWithContext) Error() string {
-	return c.message
+	if c.cause != nil {
+		return fmt.Sprintf("%s: %v", c.GetMessage(), c.cause)
+	}
+	return c.GetMessage()
+}
+
+// GetMessage returns the client-facing message in the default locale, without the underlying cause
+func (c *CodeErrWithContext) GetMessage() string {
+	return c.LocalizedMessage(DefaultLocale)
+}
+
+// LocalizedMessage returns the client-facing message translated to locale
+func (c *CodeErrWithContext) LocalizedMessage(locale string) string {
+	if c.template != "" {
+		if tmpl, ok := lookupTemplate(locale, c.template); ok {
+			return renderTemplate(tmpl, c.params)
+		}
+	}
+	message := c.CodeErr.LocalizedMessage(locale)
+	if c.detail != "" {
+		return fmt.Sprintf("%s: %s", message, c.detail)
+	}
+	return message
+}
+
+// Cause returns the underlying error, or nil if none was attached
+func (c *CodeErrWithContext) Cause() error {
+	return c.cause
+}
+
+// StackTrace returns the stack captured when the error was created,
+// or an empty string if stack capture is disabled
+func (c *CodeErrWithContext) StackTrace() string {
+	return formatStack(c.stack)
 }
 
 // ToGRPCStatus converts CodeErrWithContext to gRPC status
 func (c *CodeErrWithContext) ToGRPCStatus() error {
-	return status.Error(c.CodeErr.GetCodeErrEntity().GrpcCode, c.message)
+	return c.ToLocalizedGRPCStatus(DefaultLocale)
+}
+
+// ToLocalizedGRPCStatus converts CodeErrWithContext to gRPC status with the message translated to locale
+func (c *CodeErrWithContext) ToLocalizedGRPCStatus(locale string) error {
+	return newGRPCStatus(c.GetCodeErrEntity(), locale, c.LocalizedMessage(locale))
 }
 
-// Unwrap returns the underlying CodeErr for errors.Is/As compatibility
-func (c *CodeErrWithContext) Unwrap() error {
-	return c.CodeErr
+// Unwrap returns the underlying CodeErr and cause for errors.Is/As compatibility
+func (c *CodeErrWithContext) Unwrap() []error {
+	if c.cause != nil {
+		return []error{c.CodeErr, c.cause}
+	}
+	return []error{c.CodeErr}
 }
 
 // IsErrorCode checks if an error is of a specific code, handling wrapped errors
 func IsErrorCode(err error, code CodeErr) bool {
-	if err == code {
-		return true
-	}
-	// Handle CodeErrWithContext
-	if contextErr, ok := err.(*CodeErrWithContext); ok {
-		return contextErr.CodeErr == code
+	return errors.Is(err, code)
+}
+
+// AsCodeErrWithContext finds the first CodeErrWithContext in err's chain.
+// A bare CodeErr found in the chain is promoted to a CodeErrWithContext without a cause.
+func AsCodeErrWithContext(err error) (*CodeErrWithContext, bool) {
+	var contextErr *CodeErrWithContext
+	if errors.As(err, &contextErr) {
+		return contextErr, true
 	}
-	// Handle other wrapped errors using errors.Is
 	var codeErr CodeErr
 	if errors.As(err, &codeErr) {
-		return codeErr == code
+		return &CodeErrWithContext{CodeErr: codeErr}, true
 	}
-	return false
+	return nil, false
 }
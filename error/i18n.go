@@ -0,0 +1,112 @@
+package error
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is used when no requested locale matches an available catalog
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// Params holds named values substituted into message templates, e.g. {id}
+type Params map[string]interface{}
+
+var (
+	// catalogs maps locale -> key -> message template.
+	// Keys are error codes (ERR404P17) or error code plus template name (ERR404P17.by_id).
+	// The English catalog holds only templates; English code messages live in codeErrMap.
+	catalogs       map[string]map[string]string
+	localeMatcher  language.Matcher
+	catalogLocales []string
+)
+
+func init() {
+	if err := loadCatalogs(); err != nil {
+		panic(fmt.Sprintf("failed to load error message catalogs: %v", err))
+	}
+}
+
+// loadCatalogs reads every embedded locales/<locale>.json file
+func loadCatalogs() error {
+	entries, err := localeFS.ReadDir("locales")
+	if err != nil {
+		return err
+	}
+
+	catalogs = make(map[string]map[string]string, len(entries))
+	// The default locale must come first so the matcher falls back to it
+	tags := []language.Tag{language.Make(DefaultLocale)}
+	catalogLocales = []string{DefaultLocale}
+
+	for _, entry := range entries {
+		data, err := localeFS.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			return err
+		}
+		var catalog map[string]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+
+		locale := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		catalogs[locale] = catalog
+		if locale != DefaultLocale {
+			tags = append(tags, language.Make(locale))
+			catalogLocales = append(catalogLocales, locale)
+		}
+	}
+
+	localeMatcher = language.NewMatcher(tags)
+	return nil
+}
+
+// NegotiateLocale picks the best available catalog locale for an Accept-Language value
+func NegotiateLocale(acceptLanguage string) string {
+	if acceptLanguage == "" {
+		return DefaultLocale
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+	_, index, _ := localeMatcher.Match(tags...)
+	return catalogLocales[index]
+}
+
+// lookupTemplate finds a message template for key, falling back to the default locale
+func lookupTemplate(locale, key string) (string, bool) {
+	if tmpl, ok := catalogs[locale][key]; ok {
+		return tmpl, true
+	}
+	tmpl, ok := catalogs[DefaultLocale][key]
+	return tmpl, ok
+}
+
+// renderTemplate replaces {name} placeholders in tmpl with values from params
+func renderTemplate(tmpl string, params Params) string {
+	if len(params) == 0 {
+		return tmpl
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(tmpl)
+}
+
+// LocalizedMessage returns the error message translated to locale, or the message of its
+// CodeErrEntity when the locale has no translation
+func (c CodeErr) LocalizedMessage(locale string) string {
+	if tmpl, ok := lookupTemplate(locale, c.GetCode()); ok {
+		return tmpl
+	}
+	return c.GetMessage()
+}
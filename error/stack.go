@@ -0,0 +1,47 @@
+package error
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// maxStackDepth limits the number of frames captured per error
+const maxStackDepth = 32
+
+var stackTraceEnabled atomic.Bool
+
+// SetStackTraceEnabled toggles stack capture for errors created via WithMessage and Wrap.
+// Capturing stacks has a cost, so it is disabled by default.
+func SetStackTraceEnabled(enabled bool) {
+	stackTraceEnabled.Store(enabled)
+}
+
+// captureStack records the caller's stack if stack capture is enabled
+func captureStack() []uintptr {
+	if !stackTraceEnabled.Load() {
+		return nil
+	}
+	pcs := make([]uintptr, maxStackDepth)
+	// Skip runtime.Callers, captureStack, newCodeErrWithContext and its constructor (WithMessage, WithTemplate or Wrap)
+	n := runtime.Callers(4, pcs)
+	return pcs[:n]
+}
+
+// formatStack renders program counters as "function\n\tfile:line" lines
+func formatStack(pcs []uintptr) string {
+	if len(pcs) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&sb, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,76 @@
+// Package etag turns entity versions into entity tags and evaluates If-Match and
+// If-None-Match conditions against them.
+//
+// Tags are opaque strings without quotes, as carried in etag message fields; Quote adds
+// the quotes of the HTTP ETag header.
+package etag
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Any is the condition matching every existing entity
+const Any = "*"
+
+// FromVersion returns the tag of an entity version
+func FromVersion(version int64) string {
+	return strconv.FormatInt(version, 10)
+}
+
+// ForFields returns the tag of an entity version rendered with only fields, all when
+// empty. A partial representation gets its own tag, since a strong tag identifies one
+// representation, so it never satisfies If-Match for an update of the entity.
+func ForFields(version int64, fields []string) string {
+	if len(fields) == 0 {
+		return FromVersion(version)
+	}
+	sorted := slices.Compact(slices.Sorted(slices.Values(fields)))
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.Join(sorted, ",")))
+	return fmt.Sprintf("%d-%08x", version, hash.Sum32())
+}
+
+// Quote returns tag as an HTTP entity tag
+func Quote(tag string) string {
+	return `"` + tag + `"`
+}
+
+// Unquote returns the tag of an HTTP entity tag, which may be weak (W/"...") or already bare
+func Unquote(entityTag string) string {
+	entityTag = strings.TrimPrefix(strings.TrimSpace(entityTag), "W/")
+	if len(entityTag) >= 2 && entityTag[0] == '"' && entityTag[len(entityTag)-1] == '"' {
+		return entityTag[1 : len(entityTag)-1]
+	}
+	return entityTag
+}
+
+// Match reports whether tag satisfies condition: "*", or a comma-separated list of
+// entity tags that are quoted, weak (W/"...") or bare as sent in etag fields. Tags are
+// compared weakly, ignoring W/, which is what If-None-Match requires.
+func Match(condition, tag string) bool {
+	for _, candidate := range strings.Split(condition, ",") {
+		if strings.TrimSpace(candidate) == Any || Unquote(candidate) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchStrong reports whether tag satisfies condition like Match, but compares strongly
+// as If-Match requires (RFC 7232 section 3.1): a weak tag (W/"...") never matches.
+func MatchStrong(condition, tag string) bool {
+	for _, candidate := range strings.Split(condition, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == Any {
+			return true
+		}
+		if !strings.HasPrefix(candidate, "W/") && Unquote(candidate) == tag {
+			return true
+		}
+	}
+	return false
+}
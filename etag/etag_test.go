@@ -0,0 +1,44 @@
+package etag
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		condition string
+		tag       string
+		weak      bool
+		strong    bool
+	}{
+		{condition: "*", tag: "3", weak: true, strong: true},
+		{condition: `"3"`, tag: "3", weak: true, strong: true},
+		{condition: "3", tag: "3", weak: true, strong: true},
+		{condition: `W/"3"`, tag: "3", weak: true, strong: false},
+		{condition: `"2", "3"`, tag: "3", weak: true, strong: true},
+		{condition: `W/"2", W/"3"`, tag: "3", weak: true, strong: false},
+		{condition: `"2"`, tag: "3", weak: false, strong: false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.condition, tt.tag); got != tt.weak {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.condition, tt.tag, got, tt.weak)
+		}
+		if got := MatchStrong(tt.condition, tt.tag); got != tt.strong {
+			t.Errorf("MatchStrong(%q, %q) = %v, want %v", tt.condition, tt.tag, got, tt.strong)
+		}
+	}
+}
+
+func TestForFields(t *testing.T) {
+	if got := ForFields(3, nil); got != FromVersion(3) {
+		t.Errorf("ForFields(3, nil) = %q, want the full representation tag %q", got, FromVersion(3))
+	}
+	partial := ForFields(3, []string{"name", "etag"})
+	if partial == FromVersion(3) {
+		t.Errorf("ForFields(3, [name etag]) = %q, want a tag other than the full representation's", partial)
+	}
+	if got := ForFields(3, []string{"etag", "name", "name"}); got != partial {
+		t.Errorf("ForFields ignoring order and duplicates = %q, want %q", got, partial)
+	}
+	if got := ForFields(4, []string{"name", "etag"}); got == partial {
+		t.Errorf("ForFields(4, ...) = %q, want a tag differing from version 3", got)
+	}
+}
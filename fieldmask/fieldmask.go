@@ -0,0 +1,74 @@
+// Package fieldmask validates google.protobuf.FieldMask values against message descriptors
+// and applies read masks to responses.
+//
+// Only top-level fields are supported: a path names one field of the message, and a mask
+// of the single path "*" selects every field.
+package fieldmask
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// All is the path selecting every field
+const All = "*"
+
+// Validate reports whether every path of mask names a top-level field of message. A nil
+// or empty mask is valid.
+func Validate(mask *fieldmaskpb.FieldMask, message proto.Message) error {
+	paths := mask.GetPaths()
+	descriptor := message.ProtoReflect().Descriptor()
+	for _, path := range paths {
+		switch {
+		case path == All && len(paths) == 1:
+			continue
+		case path == All:
+			return fmt.Errorf("%q must be the only path of a field mask", All)
+		case strings.Contains(path, "."):
+			return fmt.Errorf("nested field mask path %q is not supported", path)
+		case descriptor.Fields().ByName(protoreflect.Name(path)) == nil:
+			return fmt.Errorf("%s has no field %q", descriptor.Name(), path)
+		}
+	}
+	return nil
+}
+
+// Paths returns the deduplicated paths of mask, nil when the mask is empty or "*" so that
+// callers can treat both as selecting every field
+func Paths(mask *fieldmaskpb.FieldMask) []string {
+	var paths []string
+	seen := make(map[string]bool, len(mask.GetPaths()))
+	for _, path := range mask.GetPaths() {
+		if path == All {
+			return nil
+		}
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// Prune clears the fields of message that are not in paths; empty paths keep every field
+func Prune(message proto.Message, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	keep := make(map[protoreflect.Name]bool, len(paths))
+	for _, path := range paths {
+		keep[protoreflect.Name(path)] = true
+	}
+
+	reflected := message.ProtoReflect()
+	reflected.Range(func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if !keep[field.Name()] {
+			reflected.Clear(field)
+		}
+		return true
+	})
+}
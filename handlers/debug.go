@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"runtime/pprof"
+	"time"
+
+	"github.com/harryosmar/protobuf-go/config"
+)
+
+// GCStatsResponse represents garbage collector and heap statistics
+type GCStatsResponse struct {
+	NumGC          int64            `json:"num_gc"`
+	LastGC         time.Time        `json:"last_gc"`
+	PauseTotal     string           `json:"pause_total"`
+	RecentPauses   []string         `json:"recent_pauses"`
+	HeapAllocBytes uint64           `json:"heap_alloc_bytes"`
+	HeapSysBytes   uint64           `json:"heap_sys_bytes"`
+	HeapObjects    uint64           `json:"heap_objects"`
+	NextGCBytes    uint64           `json:"next_gc_bytes"`
+	Goroutines     int              `json:"goroutines"`
+	GCPercent      int              `json:"gc_percent"`
+	MemoryLimit    int64            `json:"memory_limit_bytes"`
+	Build          *debug.BuildInfo `json:"build,omitempty"`
+}
+
+// GoroutineDumpHandler writes the stack traces of all goroutines as plain text
+func GoroutineDumpHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		pprof.Lookup("goroutine").WriteTo(w, 2)
+	}
+}
+
+// GCStatsHandler returns garbage collector and heap statistics
+func GCStatsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var gcStats debug.GCStats
+		debug.ReadGCStats(&gcStats)
+
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+
+		// Read GOGC without changing it; debug.SetGCPercent(-1) would disable the collector
+		gcPercent := []metrics.Sample{{Name: "/gc/gogc:percent"}}
+		metrics.Read(gcPercent)
+
+		response := GCStatsResponse{
+			NumGC:          gcStats.NumGC,
+			LastGC:         gcStats.LastGC,
+			PauseTotal:     gcStats.PauseTotal.String(),
+			RecentPauses:   make([]string, 0, len(gcStats.Pause)),
+			HeapAllocBytes: memStats.HeapAlloc,
+			HeapSysBytes:   memStats.HeapSys,
+			HeapObjects:    memStats.HeapObjects,
+			NextGCBytes:    memStats.NextGC,
+			Goroutines:     runtime.NumGoroutine(),
+			GCPercent:      int(gcPercent[0].Value.Uint64()),
+			MemoryLimit:    debug.SetMemoryLimit(-1), // Negative input reads the limit without changing it
+		}
+		for _, pause := range gcStats.Pause {
+			response.RecentPauses = append(response.RecentPauses, pause.String())
+		}
+		if build, ok := debug.ReadBuildInfo(); ok {
+			response.Build = build
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// ConfigHandler returns the effective configuration with secrets redacted
+func ConfigHandler(current func() *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(current().Redacted())
+	}
+}
@@ -1,30 +1,109 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/harryosmar/protobuf-go/config"
+	"github.com/harryosmar/protobuf-go/logger"
+	"go.uber.org/zap"
 )
 
+// healthCheckTimeout bounds how long the health endpoint waits for all checks
+const healthCheckTimeout = 5 * time.Second
+
 // HealthResponse represents the health check response
 type HealthResponse struct {
-	ServiceName string `json:"service_name"`
-	Version     string `json:"version"`
-	Status      string `json:"status"`
+	ServiceName string            `json:"service_name"`
+	Version     string            `json:"version"`
+	Status      string            `json:"status"`
+	Checks      map[string]string `json:"checks,omitempty"`
 }
 
-// HealthHandler returns the health status of the service
-func HealthHandler(cfg *config.Config) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		response := HealthResponse{
-			ServiceName: cfg.AppName,
-			Version:     cfg.AppVersion,
-			Status:      "healthy",
+// HealthCheckFunc reports whether a dependency can serve requests
+type HealthCheckFunc func(ctx context.Context) error
+
+// HealthChecker serves the health status from the latest configuration snapshot
+type HealthChecker struct {
+	cfg    atomic.Pointer[config.Config]
+	checks map[string]HealthCheckFunc
+	names  []string
+}
+
+// NewHealthChecker creates a health checker for the initial configuration
+func NewHealthChecker(cfg *config.Config) *HealthChecker {
+	checker := &HealthChecker{checks: make(map[string]HealthCheckFunc)}
+	checker.cfg.Store(cfg)
+	return checker
+}
+
+// AddCheck registers a named check; it must be called before the handler serves requests
+func (h *HealthChecker) AddCheck(name string, check HealthCheckFunc) {
+	if _, ok := h.checks[name]; !ok {
+		h.names = append(h.names, name)
+	}
+	h.checks[name] = check
+}
+
+// ApplyConfig replaces the configuration snapshot reported by the health endpoint
+func (h *HealthChecker) ApplyConfig(cfg *config.Config) error {
+	h.cfg.Store(cfg)
+	return nil
+}
+
+// ServeHTTP runs the registered checks concurrently and returns 503 when any of them fails.
+// Failed checks are reported as "unhealthy" and their errors are logged.
+func (h *HealthChecker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cfg := h.cfg.Load()
+	response := HealthResponse{
+		ServiceName: cfg.AppName,
+		Version:     cfg.AppVersion,
+		Status:      "healthy",
+	}
+	statusCode := http.StatusOK
+
+	if len(h.names) > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancel()
+
+		results := make([]error, len(h.names))
+		var wg sync.WaitGroup
+		for i, name := range h.names {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = h.checks[name](ctx)
+			}()
 		}
+		wg.Wait()
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(response)
+		response.Checks = make(map[string]string, len(h.names))
+		for i, name := range h.names {
+			if results[i] != nil {
+				// The cause may name hosts or credentials, so it is only logged
+				logger.FromContext(r.Context()).Warn("Health check failed",
+					zap.String("check", name),
+					zap.Error(results[i]),
+				)
+				response.Checks[name] = "unhealthy"
+				response.Status = "unhealthy"
+				statusCode = http.StatusServiceUnavailable
+				continue
+			}
+			response.Checks[name] = "ok"
+		}
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
+// HealthHandler returns the health status of the service
+func HealthHandler(cfg *config.Config) http.HandlerFunc {
+	return NewHealthChecker(cfg).ServeHTTP
 }
@@ -2,17 +2,131 @@ package logger
 
 import (
 	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-const LoggerContextKey = "logger"
+// contextKey is unexported so keys cannot collide with values set by other packages
+type contextKey int
+
+const (
+	loggerContextKey contextKey = iota
+	debugForcedContextKey
+)
+
+var (
+	// atomicLevel is the runtime-adjustable level shared by every logger built by InitLogger
+	atomicLevel = zap.NewAtomicLevelAt(zap.InfoLevel)
+	// configuredLevel is the level requested at startup, by SetLevel or by LevelHandler,
+	// restored by ToggleDebug
+	configuredLevel = zap.NewAtomicLevelAt(zap.InfoLevel)
+	// levelMutex serializes changes to the two levels
+	levelMutex sync.Mutex
+	// fallbackLogger is returned by FromContext when the context carries no logger
+	fallbackLogger atomic.Pointer[zap.Logger]
+)
+
+func init() {
+	// Used until InitLogger replaces it, e.g. in tools and tests that never call InitLogger
+	logger, err := zap.NewProduction()
+	if err != nil {
+		logger = zap.NewNop()
+	}
+	fallbackLogger.Store(logger)
+}
+
+// InitLogger creates a new zap logger instance whose level can be changed at runtime via Level.
+// An unparseable level falls back to info.
+func InitLogger(level string) (*zap.Logger, error) {
+	parsed, err := zapcore.ParseLevel(level)
+	if err != nil {
+		parsed = zap.InfoLevel
+	}
+	configuredLevel.SetLevel(parsed)
+	atomicLevel.SetLevel(parsed)
 
-// InitLogger creates a new zap logger instance
-func InitLogger() (*zap.Logger, error) {
 	config := zap.NewProductionConfig()
-	config.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
-	return config.Build()
+	// The core itself accepts every level; filtering happens in levelFilterCore so
+	// individual request loggers can bypass it with ForceDebug.
+	config.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
+	logger, err := config.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &levelFilterCore{Core: core, level: atomicLevel}
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	SetFallback(logger)
+	return logger, nil
+}
+
+// SetFallback sets the logger returned by FromContext when the context carries none
+func SetFallback(logger *zap.Logger) {
+	fallbackLogger.Store(logger)
+}
+
+// Level returns the runtime-adjustable log level
+func Level() zap.AtomicLevel {
+	return atomicLevel
+}
+
+// LevelHandler serves the log level (GET to read, PUT {"level":"debug"} to change). A PUT
+// also changes the configured level restored by ToggleDebug, unless debug is toggled on.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		levelMutex.Lock()
+		defer levelMutex.Unlock()
+
+		toggled := debugToggled()
+		atomicLevel.ServeHTTP(w, r)
+		if r.Method == http.MethodPut && !toggled {
+			configuredLevel.SetLevel(atomicLevel.Level())
+		}
+	})
+}
+
+// SetLevel changes the configured level, e.g. after a configuration reload
+func SetLevel(level string) error {
+	parsed, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	levelMutex.Lock()
+	defer levelMutex.Unlock()
+	configuredLevel.SetLevel(parsed)
+	atomicLevel.SetLevel(parsed)
+	return nil
+}
+
+// ToggleDebug switches between debug and the configured level and returns the new level
+func ToggleDebug() zapcore.Level {
+	levelMutex.Lock()
+	defer levelMutex.Unlock()
+	if debugToggled() {
+		atomicLevel.SetLevel(configuredLevel.Level())
+	} else {
+		atomicLevel.SetLevel(zap.DebugLevel)
+	}
+	return atomicLevel.Level()
+}
+
+// debugToggled reports whether ToggleDebug turned debug on over another configured level
+func debugToggled() bool {
+	return atomicLevel.Level() == zap.DebugLevel && configuredLevel.Level() != zap.DebugLevel
+}
+
+// ForceDebug returns a logger that logs at debug level regardless of the global level
+func ForceDebug(logger *zap.Logger) *zap.Logger {
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if filtered, ok := core.(*levelFilterCore); ok {
+			return filtered.Core
+		}
+		return core
+	}))
 }
 
 // WithRequestID creates a logger with request_id field pre-populated
@@ -22,15 +136,71 @@ func WithRequestID(logger *zap.Logger, requestID string) *zap.Logger {
 
 // FromContext extracts the logger from context
 func FromContext(ctx context.Context) *zap.Logger {
-	if logger, ok := ctx.Value(LoggerContextKey).(*zap.Logger); ok {
+	if logger, ok := ctx.Value(loggerContextKey).(*zap.Logger); ok {
 		return logger
 	}
-	// Fallback to a basic logger if not found in context
-	logger, _ := zap.NewProduction()
-	return logger
+	// Fallback to the application logger if not found in context
+	return fallbackLogger.Load()
 }
 
 // ToContext adds logger to context
 func ToContext(ctx context.Context, logger *zap.Logger) context.Context {
-	return context.WithValue(ctx, LoggerContextKey, logger)
+	return context.WithValue(ctx, loggerContextKey, logger)
+}
+
+// WithFields adds fields to the context logger so every later log line for the request carries them
+func WithFields(ctx context.Context, fields ...zap.Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	return ToContext(ctx, FromContext(ctx).With(fields...))
+}
+
+// WithUserID adds the user_id field to the context logger
+func WithUserID(ctx context.Context, userID int64) context.Context {
+	return WithFields(ctx, zap.Int64("user_id", userID))
+}
+
+// WithTenant adds the tenant field to the context logger
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return WithFields(ctx, zap.String("tenant", tenant))
+}
+
+// WithTraceID adds the trace_id field to the context logger
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return WithFields(ctx, zap.String("trace_id", traceID))
+}
+
+// WithForcedDebug replaces the context logger with a debug-level one and marks the request
+// so that log sampling is bypassed
+func WithForcedDebug(ctx context.Context) context.Context {
+	ctx = ToContext(ctx, ForceDebug(FromContext(ctx)))
+	return context.WithValue(ctx, debugForcedContextKey, true)
+}
+
+// IsDebugForced reports whether debug logging was forced for this request
+func IsDebugForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(debugForcedContextKey).(bool)
+	return forced
+}
+
+// levelFilterCore drops entries below the shared atomic level
+type levelFilterCore struct {
+	zapcore.Core
+	level zap.AtomicLevel
+}
+
+func (c *levelFilterCore) Enabled(lvl zapcore.Level) bool {
+	return c.level.Enabled(lvl) && c.Core.Enabled(lvl)
+}
+
+func (c *levelFilterCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelFilterCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *levelFilterCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.level.Enabled(entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
 }
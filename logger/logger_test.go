@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelHandlerPutThenToggle(t *testing.T) {
+	if err := SetLevel("info"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/log/level", strings.NewReader(`{"level":"warn"}`))
+	rec := httptest.NewRecorder()
+	LevelHandler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	if got := ToggleDebug(); got != zapcore.DebugLevel {
+		t.Errorf("first toggle = %v, want debug", got)
+	}
+	if got := ToggleDebug(); got != zapcore.WarnLevel {
+		t.Errorf("second toggle = %v, want the level set by PUT, warn", got)
+	}
+}
+
+func TestLevelHandlerPutWhileDebugToggled(t *testing.T) {
+	if err := SetLevel("info"); err != nil {
+		t.Fatal(err)
+	}
+	ToggleDebug()
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/log/level", strings.NewReader(`{"level":"error"}`))
+	LevelHandler().ServeHTTP(httptest.NewRecorder(), req)
+
+	if got := ToggleDebug(); got != zapcore.DebugLevel {
+		t.Errorf("first toggle = %v, want debug", got)
+	}
+	if got := ToggleDebug(); got != zapcore.InfoLevel {
+		t.Errorf("second toggle = %v, want the configured level, info", got)
+	}
+}
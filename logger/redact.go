@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync/atomic"
+	"unicode/utf8"
+
+	logpb "github.com/harryosmar/protobuf-go/gen/log"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// RedactStrategy controls how fields marked with (log.redact) or debug_redact are rendered
+type RedactStrategy string
+
+const (
+	RedactMask RedactStrategy = "mask" // keep the first character, e.g. "j***"
+	RedactHash RedactStrategy = "hash" // replace with a truncated SHA-256 so values can be correlated
+	RedactDrop RedactStrategy = "drop" // remove the field entirely
+
+	redactedPlaceholder = "***"
+)
+
+var redactStrategy atomic.Value
+
+func init() {
+	redactStrategy.Store(RedactMask)
+}
+
+// SetRedactStrategy sets the strategy used by MarshalProto; unknown values fall back to mask
+func SetRedactStrategy(strategy RedactStrategy) {
+	switch strategy {
+	case RedactMask, RedactHash, RedactDrop:
+	default:
+		strategy = RedactMask
+	}
+	redactStrategy.Store(strategy)
+}
+
+// MarshalProto renders msg as protojson with sensitive fields redacted
+func MarshalProto(msg proto.Message) ([]byte, error) {
+	return protojson.Marshal(Redact(msg))
+}
+
+// Proto returns a zap field with msg rendered by MarshalProto
+func Proto(key string, msg proto.Message) zap.Field {
+	data, err := MarshalProto(msg)
+	if err != nil {
+		return zap.String(key, "<unrenderable: "+err.Error()+">")
+	}
+	return zap.ByteString(key, data)
+}
+
+// Redact returns a copy of msg with sensitive fields redacted recursively.
+// The original message is never modified.
+func Redact(msg proto.Message) proto.Message {
+	if msg == nil {
+		return nil
+	}
+	clone := proto.Clone(msg)
+	redactMessage(clone.ProtoReflect(), redactStrategy.Load().(RedactStrategy))
+	return clone
+}
+
+// redactMessage walks every populated field of m, redacting marked fields
+// and descending into nested messages, lists, maps and Any values
+func redactMessage(m protoreflect.Message, strategy RedactStrategy) {
+	if any, ok := m.Interface().(*anypb.Any); ok {
+		redactAny(any, strategy)
+		return
+	}
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if isRedacted(fd) {
+			redactField(m, fd, v, strategy)
+			return true
+		}
+
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				redactMessage(list.Get(i).Message(), strategy)
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				redactMessage(mv.Message(), strategy)
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			redactMessage(v.Message(), strategy)
+		}
+		return true
+	})
+}
+
+// redactAny redacts the packed message of an Any in place when its type is known
+func redactAny(any *anypb.Any, strategy RedactStrategy) {
+	inner, err := any.UnmarshalNew()
+	if err != nil {
+		return
+	}
+	redactMessage(inner.ProtoReflect(), strategy)
+	if packed, err := anypb.New(inner); err == nil {
+		any.Value = packed.Value
+	}
+}
+
+// isRedacted reports whether fd is marked with (log.redact) or debug_redact
+func isRedacted(fd protoreflect.FieldDescriptor) bool {
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	if !ok || opts == nil {
+		return false
+	}
+	if opts.GetDebugRedact() {
+		return true
+	}
+	redact, _ := proto.GetExtension(opts, logpb.E_Redact).(bool)
+	return redact
+}
+
+// redactField applies strategy to a marked field. Only strings can be masked or hashed;
+// any other kind of value is dropped.
+func redactField(m protoreflect.Message, fd protoreflect.FieldDescriptor, v protoreflect.Value, strategy RedactStrategy) {
+	if strategy == RedactDrop || fd.Kind() != protoreflect.StringKind || fd.IsMap() {
+		m.Clear(fd)
+		return
+	}
+
+	if fd.IsList() {
+		list := v.List()
+		for i := 0; i < list.Len(); i++ {
+			list.Set(i, protoreflect.ValueOfString(redactString(list.Get(i).String(), strategy)))
+		}
+		return
+	}
+	m.Set(fd, protoreflect.ValueOfString(redactString(v.String(), strategy)))
+}
+
+// redactString masks or hashes a single string value
+func redactString(value string, strategy RedactStrategy) string {
+	if strategy == RedactHash {
+		sum := sha256.Sum256([]byte(value))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	}
+	if utf8.RuneCountInString(value) <= 1 {
+		return redactedPlaceholder
+	}
+	first, _ := utf8.DecodeRuneInString(value)
+	return string(first) + redactedPlaceholder
+}
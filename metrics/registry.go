@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NewRegistry creates a dedicated registry with Go runtime, process and build info collectors
+func NewRegistry(appName, appVersion string) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		NewBuildInfoCollector(appName, appVersion),
+	)
+	return registry
+}
+
+// NewBuildInfoCollector exposes a constant app_build_info gauge labelled with the application version
+func NewBuildInfoCollector(appName, appVersion string) prometheus.Collector {
+	buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "app_build_info",
+		Help: "Build information about the running application, always 1",
+		ConstLabels: prometheus.Labels{
+			"app_name":   appName,
+			"version":    appVersion,
+			"go_version": runtime.Version(),
+		},
+	})
+	buildInfo.Set(1)
+	return buildInfo
+}
+
+// Handler serves the metrics gathered by registry, including scrape metrics for the handler itself
+func Handler(registry *prometheus.Registry) http.Handler {
+	return promhttp.InstrumentMetricHandler(registry,
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+			Registry:          registry, // Report promhttp errors on the same registry
+			EnableOpenMetrics: true,     // Required to expose exemplars
+		}),
+	)
+}
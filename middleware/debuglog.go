@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"github.com/harryosmar/protobuf-go/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const DebugLogHeader = "x-debug-log"
+
+// DebugLogInterceptor forces debug-level logging for a request when the caller sends
+// the x-debug-log header with the configured token, which is called for every request so
+// rotated tokens apply. An empty token disables the feature.
+// It must run after RequestIDInterceptor so the request logger is already in context.
+func DebugLogInterceptor(token func() string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if token := token(); token != "" && isTrustedDebugRequest(ctx, token) {
+			ctx = logger.WithForcedDebug(ctx)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// isTrustedDebugRequest reports whether the x-debug-log header matches token
+func isTrustedDebugRequest(ctx context.Context, token string) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	values := md.Get(DebugLogHeader)
+	if len(values) == 0 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(values[0]), []byte(token)) == 1
+}
@@ -2,28 +2,67 @@ package middleware
 
 import (
 	"context"
+	"errors"
 
 	error2 "github.com/harryosmar/protobuf-go/error"
+	"github.com/harryosmar/protobuf-go/logger"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-// ErrorConversionInterceptor automatically converts CodeErr to gRPC status
+// ErrorConversionInterceptor automatically converts CodeErr to gRPC status.
+// Messages are localized from the accept-language metadata. Underlying causes are
+// logged server-side only; errors that are not a CodeErr are returned to clients
+// as a sanitized internal server error.
 func ErrorConversionInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		resp, err := handler(ctx, req)
 		if err != nil {
-			// Convert CodeErr to gRPC status automatically
-			if codeErr, ok := err.(error2.CodeErr); ok {
-				return resp, codeErr.ToGRPCStatus()
+			return resp, convertError(ctx, info.FullMethod, err)
+		}
+		return resp, nil
+	}
+}
+
+// convertError maps err to a gRPC status error, logging any detail that is not sent to the client
+func convertError(ctx context.Context, method string, err error) error {
+	log := logger.FromContext(ctx)
+	locale := LocaleFromContext(ctx)
+
+	// Convert CodeErr (bare, with context, or wrapped) to gRPC status automatically
+	if contextErr, ok := error2.AsCodeErrWithContext(err); ok {
+		if cause := contextErr.Cause(); cause != nil {
+			fields := []zap.Field{
+				zap.String("method", method),
+				zap.String("error_code", contextErr.GetCode()),
+				zap.Error(cause),
 			}
-			if contextErr, ok := err.(*error2.CodeErrWithContext); ok {
-				return resp, contextErr.ToGRPCStatus()
+			if stack := contextErr.StackTrace(); stack != "" {
+				fields = append(fields, zap.String("error_stack", stack))
 			}
-			// For other errors, return as Internal error
-			return resp, status.Error(codes.Internal, err.Error())
+			log.Error("Request failed with underlying cause", fields...)
 		}
-		return resp, nil
+		return contextErr.ToLocalizedGRPCStatus(locale)
+	}
+
+	// Context errors map to their gRPC equivalents
+	switch {
+	case errors.Is(err, context.Canceled):
+		return error2.ErrCancelled.ToLocalizedGRPCStatus(locale)
+	case errors.Is(err, context.DeadlineExceeded):
+		return error2.ErrDeadlineExceeded.ToLocalizedGRPCStatus(locale)
 	}
+
+	// Errors that already carry a gRPC status are passed through unchanged
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	// For other errors, log the detail and return a sanitized Internal error
+	log.Error("Unhandled error converted to internal server error",
+		zap.String("method", method),
+		zap.Error(err),
+	)
+	return error2.ErrInternalServer.ToLocalizedGRPCStatus(locale)
 }
@@ -0,0 +1,236 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+// MetricsConfig holds Prometheus instrumentation configuration
+type MetricsConfig struct {
+	DurationBuckets  []float64 // Request duration buckets in seconds, defaults to prometheus.DefBuckets
+	SizeBuckets      []float64 // Message size buckets in bytes, defaults to 64B..4MB
+	NativeHistograms bool      // Also expose native (sparse) histograms
+
+	Registerer prometheus.Registerer // Registry the metrics are registered with, defaults to prometheus.DefaultRegisterer
+}
+
+// GRPCMetrics holds the gRPC server RED metrics and the panic and rate limit counters
+type GRPCMetrics struct {
+	requestsTotal     *prometheus.CounterVec
+	requestDuration   *prometheus.HistogramVec
+	requestsInFlight  *prometheus.GaugeVec
+	connections       prometheus.Gauge
+	requestSize       *prometheus.HistogramVec
+	responseSize      *prometheus.HistogramVec
+	panicsTotal       *prometheus.CounterVec
+	rateLimitExceeded *prometheus.CounterVec
+}
+
+// NewGRPCMetrics creates and registers the gRPC server metrics
+func NewGRPCMetrics(config MetricsConfig) *GRPCMetrics {
+	if len(config.DurationBuckets) == 0 {
+		config.DurationBuckets = prometheus.DefBuckets
+	}
+	if len(config.SizeBuckets) == 0 {
+		config.SizeBuckets = prometheus.ExponentialBuckets(64, 4, 9) // 64B to 4MB
+	}
+	if config.Registerer == nil {
+		config.Registerer = prometheus.DefaultRegisterer
+	}
+	factory := promauto.With(config.Registerer)
+
+	histogramOpts := func(name, help string, buckets []float64) prometheus.HistogramOpts {
+		opts := prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}
+		if config.NativeHistograms {
+			opts.NativeHistogramBucketFactor = 1.1
+			opts.NativeHistogramMaxBucketNumber = 100
+			opts.NativeHistogramMinResetDuration = time.Hour
+		}
+		return opts
+	}
+
+	return &GRPCMetrics{
+		requestsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "grpc_requests_total",
+				Help: "Total number of gRPC requests",
+			},
+			[]string{"grpc_service", "grpc_method", "grpc_code"},
+		),
+		requestDuration: factory.NewHistogramVec(
+			histogramOpts("grpc_request_duration_seconds", "Duration of gRPC requests in seconds", config.DurationBuckets),
+			[]string{"grpc_service", "grpc_method", "grpc_code"},
+		),
+		requestsInFlight: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "grpc_requests_in_flight",
+				Help: "Number of gRPC requests currently being handled",
+			},
+			[]string{"grpc_service", "grpc_method"},
+		),
+		connections: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "grpc_active_connections",
+				Help: "Number of open gRPC client connections",
+			},
+		),
+		requestSize: factory.NewHistogramVec(
+			histogramOpts("grpc_request_size_bytes", "Size of received gRPC request messages in bytes", config.SizeBuckets),
+			[]string{"grpc_service", "grpc_method"},
+		),
+		responseSize: factory.NewHistogramVec(
+			histogramOpts("grpc_response_size_bytes", "Size of sent gRPC response messages in bytes", config.SizeBuckets),
+			[]string{"grpc_service", "grpc_method"},
+		),
+		panicsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "grpc_panics_total",
+				Help: "Total number of panics recovered in gRPC handlers",
+			},
+			[]string{"method"},
+		),
+		rateLimitExceeded: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "rate_limit_exceeded_total",
+				Help: "Total number of rate limit exceeded events",
+			},
+			[]string{"method", "key"},
+		),
+	}
+}
+
+// MetricsInterceptor collects Prometheus metrics for gRPC requests
+func MetricsInterceptor(metrics *GRPCMetrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		startTime := time.Now()
+		service, method := splitFullMethod(info.FullMethod)
+
+		// Track in-flight requests
+		inFlight := metrics.requestsInFlight.WithLabelValues(service, method)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		// Call the handler
+		resp, err := handler(ctx, req)
+
+		// Calculate duration
+		duration := time.Since(startTime)
+
+		// Determine gRPC status code
+		statusCode := codes.OK
+		if err != nil {
+			if st, ok := status.FromError(err); ok {
+				statusCode = st.Code()
+			} else {
+				statusCode = codes.Internal
+			}
+		}
+
+		// Record metrics
+		labels := prometheus.Labels{
+			"grpc_service": service,
+			"grpc_method":  method,
+			"grpc_code":    statusCode.String(),
+		}
+
+		metrics.requestsTotal.With(labels).Inc()
+		observeWithExemplar(ctx, metrics.requestDuration.With(labels), duration.Seconds())
+
+		return resp, err
+	}
+}
+
+// observeWithExemplar attaches the trace ID of a sampled span as an exemplar
+func observeWithExemplar(ctx context.Context, observer prometheus.Observer, value float64) {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok && spanCtx.IsSampled() {
+		exemplarObserver.ObserveWithExemplar(value, prometheus.Labels{"trace_id": spanCtx.TraceID().String()})
+		return
+	}
+	observer.Observe(value)
+}
+
+// splitFullMethod splits "/package.Service/Method" into service and method names
+func splitFullMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
+
+// metricsStatsHandler counts connections and message sizes, which interceptors cannot observe
+type metricsStatsHandler struct {
+	metrics *GRPCMetrics
+}
+
+type rpcMethodContextKey struct{}
+
+// NewMetricsStatsHandler returns a stats.Handler recording connection count and message sizes.
+// Install it with grpc.StatsHandler alongside MetricsInterceptor.
+func NewMetricsStatsHandler(metrics *GRPCMetrics) stats.Handler {
+	return &metricsStatsHandler{metrics: metrics}
+}
+
+func (h *metricsStatsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, rpcMethodContextKey{}, info.FullMethodName)
+}
+
+func (h *metricsStatsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	fullMethod, ok := ctx.Value(rpcMethodContextKey{}).(string)
+	if !ok {
+		return
+	}
+	switch payload := s.(type) {
+	case *stats.InPayload:
+		service, method := splitFullMethod(fullMethod)
+		h.metrics.requestSize.WithLabelValues(service, method).Observe(float64(payload.Length))
+	case *stats.OutPayload:
+		service, method := splitFullMethod(fullMethod)
+		h.metrics.responseSize.WithLabelValues(service, method).Observe(float64(payload.Length))
+	}
+}
+
+func (h *metricsStatsHandler) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *metricsStatsHandler) HandleConn(ctx context.Context, s stats.ConnStats) {
+	switch s.(type) {
+	case *stats.ConnBegin:
+		h.metrics.connections.Inc()
+	case *stats.ConnEnd:
+		h.metrics.connections.Dec()
+	}
+}
+
+// RecordRateLimitExceeded records a request rejected by the rate limiter; nil metrics
+// record nothing
+func (m *GRPCMetrics) RecordRateLimitExceeded(method, key string) {
+	if m == nil {
+		return
+	}
+	m.rateLimitExceeded.With(prometheus.Labels{
+		"method": method,
+		"key":    key,
+	}).Inc()
+}
+
+// RecordPanic records a recovered panic for the given method; nil metrics record nothing
+func (m *GRPCMetrics) RecordPanic(method string) {
+	if m == nil {
+		return
+	}
+	m.panicsTotal.With(prometheus.Labels{
+		"method": method,
+	}).Inc()
+}
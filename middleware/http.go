@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/google/uuid"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/harryosmar/protobuf-go/logger"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+// HTTPMiddleware wraps an http.Handler
+type HTTPMiddleware func(http.Handler) http.Handler
+
+// ChainHTTP wraps handler with middlewares; the first middleware is the outermost
+func ChainHTTP(handler http.Handler, middlewares ...HTTPMiddleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// HTTPRequestIDMiddleware reuses a valid X-Request-ID header or generates one, stores it in
+// the request context with a request-scoped logger and echoes it in the response header
+func HTTPRequestIDMiddleware(baseLogger *zap.Logger) HTTPMiddleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID.MatchString(requestID) {
+				requestID = uuid.New().String()
+				r.Header.Set(RequestIDHeader, requestID)
+			}
+
+			ctx := context.WithValue(r.Context(), requestIDContextKey{}, requestID)
+			requestLogger := logger.WithRequestID(baseLogger, requestID)
+			if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+				requestLogger = requestLogger.With(
+					zap.String("trace_id", spanCtx.TraceID().String()),
+					zap.String("span_id", spanCtx.SpanID().String()),
+				)
+			}
+			ctx = logger.ToContext(ctx, requestLogger)
+
+			w.Header().Set(RequestIDHeader, requestID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GatewayRequestIDMetadata forwards the HTTP request ID to gRPC metadata.
+// Use with runtime.WithMetadata so gateway and gRPC logs share one request ID.
+func GatewayRequestIDMetadata(ctx context.Context, r *http.Request) metadata.MD {
+	if requestID := GetRequestID(ctx); requestID != "" {
+		return metadata.Pairs(RequestIDHeader, requestID)
+	}
+	return nil
+}
+
+// HTTPRouteResolver maps a request to a low-cardinality route label for metrics and logs
+type HTTPRouteResolver func(r *http.Request) string
+
+// httpRouteContextKey holds a *string filled by GatewayRouteMiddleware once the gateway matched a route
+type httpRouteContextKey struct{}
+
+// GatewayRouteMiddleware records the matched gateway path pattern (e.g. /v1/users/{id=*}).
+// Use with runtime.WithMiddlewares.
+func GatewayRouteMiddleware(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		if pattern, ok := runtime.HTTPPattern(r.Context()); ok {
+			if route, ok := r.Context().Value(httpRouteContextKey{}).(*string); ok {
+				*route = pattern.String()
+			}
+		}
+		next(w, r, pathParams)
+	}
+}
+
+// ServeMuxRouteResolver resolves routes from the gateway pattern when present,
+// otherwise from the pattern registered on mux
+func ServeMuxRouteResolver(mux *http.ServeMux) HTTPRouteResolver {
+	return func(r *http.Request) string {
+		if route, ok := r.Context().Value(httpRouteContextKey{}).(*string); ok && *route != "" {
+			return *route
+		}
+		if _, pattern := mux.Handler(r); pattern != "" {
+			return pattern
+		}
+		return "unmatched"
+	}
+}
+
+// withRouteHolder prepares the request so GatewayRouteMiddleware can report the matched route
+func withRouteHolder(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(httpRouteContextKey{}).(*string); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), httpRouteContextKey{}, new(string)))
+}
+
+// HTTPMetricsMiddleware records request count, duration and in-flight requests per route
+func HTTPMetricsMiddleware(metrics *HTTPMetrics, resolveRoute HTTPRouteResolver) HTTPMiddleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = withRouteHolder(r)
+
+			metrics.requestsInFlight.Inc()
+			defer metrics.requestsInFlight.Dec()
+
+			m := httpsnoop.CaptureMetrics(next, w, r)
+
+			metrics.RecordRequest(r.Method, resolveRoute(r), m.Code, m.Duration)
+		})
+	}
+}
+
+// HTTPAccessLogMiddleware writes one structured log line per HTTP request. Requests for
+// which quiet returns true, such as health checks, are logged only when they fail; nil
+// logs every request. It must run after HTTPRequestIDMiddleware so the request logger is
+// in context.
+func HTTPAccessLogMiddleware(resolveRoute HTTPRouteResolver, quiet func(r *http.Request) bool) HTTPMiddleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = withRouteHolder(r)
+			startTime := time.Now()
+
+			m := httpsnoop.CaptureMetrics(next, w, r)
+			if m.Code < http.StatusBadRequest && quiet != nil && quiet(r) {
+				return
+			}
+
+			fields := []zap.Field{
+				zap.String("http_method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.String("route", resolveRoute(r)),
+				zap.Int("status", m.Code),
+				zap.Int64("response_bytes", m.Written),
+				zap.Duration("duration", m.Duration),
+				zap.Time("start_time", startTime),
+				zap.String("remote_addr", r.RemoteAddr),
+				zap.String("user_agent", r.UserAgent()),
+			}
+
+			log := logger.FromContext(r.Context())
+			switch {
+			case m.Code >= http.StatusInternalServerError:
+				log.Error("HTTP request completed", fields...)
+			case m.Code >= http.StatusBadRequest:
+				log.Warn("HTTP request completed", fields...)
+			default:
+				log.Info("HTTP request completed", fields...)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	error2 "github.com/harryosmar/protobuf-go/error"
+	"google.golang.org/grpc/metadata"
+)
+
+const AcceptLanguageHeader = "accept-language"
+
+// LocaleFromContext negotiates the response locale from the incoming accept-language metadata.
+// The HTTP gateway forwards the Accept-Language header with the grpcgateway- prefix.
+func LocaleFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return error2.DefaultLocale
+	}
+	for _, key := range []string{AcceptLanguageHeader, runtime.MetadataPrefix + AcceptLanguageHeader} {
+		if values := md.Get(key); len(values) > 0 {
+			return error2.NegotiateLocale(values[0])
+		}
+	}
+	return error2.DefaultLocale
+}
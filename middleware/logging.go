@@ -2,8 +2,7 @@ package middleware
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"math/rand/v2"
 	"time"
 
 	"github.com/harryosmar/protobuf-go/logger"
@@ -11,10 +10,27 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
-// LoggingInterceptor logs detailed request and response information
-func LoggingInterceptor(baseLogger *zap.Logger) grpc.UnaryServerInterceptor {
+// LoggingConfig holds request/response logging configuration
+type LoggingConfig struct {
+	MaxPayloadBytes   int                // Payloads larger than this are logged by size only
+	SuccessSampleRate float64            // Fraction [0-1] of successful requests logged; failures are always logged
+	MethodSampleRates map[string]float64 // Per-method overrides of SuccessSampleRate, keyed by full method name
+}
+
+// LoggingInterceptor logs detailed request and response information.
+// Payloads are rendered with protojson and sensitive fields are redacted.
+// Successful requests are sampled; failed and debug-forced requests are always logged.
+func LoggingInterceptor(baseLogger *zap.Logger, config LoggingConfig) grpc.UnaryServerInterceptor {
+	if config.MaxPayloadBytes <= 0 {
+		config.MaxPayloadBytes = 1024 // Default: 1KB
+	}
+	if config.SuccessSampleRate < 0 || config.SuccessSampleRate > 1 {
+		config.SuccessSampleRate = 1 // Invalid: log every request; 0 logs failures only
+	}
+
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		startTime := time.Now()
 
@@ -22,34 +38,35 @@ func LoggingInterceptor(baseLogger *zap.Logger) grpc.UnaryServerInterceptor {
 		log := logger.FromContext(ctx)
 
 		// Conditionally serialize request payload for high-traffic optimization
+		reqSize := getPayloadSize(req)
+		logReqPayload := shouldLogPayload(info.FullMethod, reqSize, config.MaxPayloadBytes)
 		var reqPayload []byte
-		var reqFields []zap.Field
 
-		// Only log payload for non-high-frequency methods or small payloads
-		if shouldLogPayload(info.FullMethod, req) {
-			reqPayload, _ = json.Marshal(req)
-			reqFields = []zap.Field{
+		// Log request start (debug only, the completion line carries the same fields)
+		if log.Core().Enabled(zap.DebugLevel) {
+			reqFields := []zap.Field{
 				zap.String("method", info.FullMethod),
-				zap.ByteString("request_payload", reqPayload),
+				zap.Int("request_size_bytes", reqSize),
 				zap.Time("start_time", startTime),
 			}
-		} else {
-			reqFields = []zap.Field{
-				zap.String("method", info.FullMethod),
-				zap.String("payload_size", getPayloadSize(req)),
-				zap.Time("start_time", startTime),
+			if logReqPayload {
+				reqPayload = renderPayload(req)
+				reqFields = append(reqFields, zap.ByteString("request_payload", reqPayload))
 			}
+			log.Debug("gRPC request received", reqFields...)
 		}
 
-		// Log request start
-		log.Info("gRPC request received", reqFields...)
-
 		// Call the handler
 		resp, err := handler(ctx, req)
 
 		// Calculate duration
 		duration := time.Since(startTime)
 
+		// Sample successful requests unless debug logging is forced for this request
+		if err == nil && !logger.IsDebugForced(ctx) && !config.sampled(info.FullMethod) {
+			return resp, err
+		}
+
 		// Determine gRPC status
 		grpcStatus := codes.OK
 		statusCode := "OK"
@@ -63,24 +80,30 @@ func LoggingInterceptor(baseLogger *zap.Logger) grpc.UnaryServerInterceptor {
 			}
 		}
 
-		// Serialize response payload (if no error)
-		var respPayload []byte
-		if resp != nil {
-			respPayload, _ = json.Marshal(resp)
-		}
-
 		// Common fields for logging
 		fields := []zap.Field{
 			zap.String("method", info.FullMethod),
 			zap.String("grpc_status", statusCode),
 			zap.Int("status_code", int(grpcStatus)),
 			zap.Duration("duration", duration),
-			zap.ByteString("request_payload", reqPayload),
+			zap.Int("request_size_bytes", reqSize),
+		}
+		if logReqPayload {
+			if reqPayload == nil {
+				reqPayload = renderPayload(req)
+			}
+			fields = append(fields, zap.ByteString("request_payload", reqPayload))
 		}
 
-		// Add response payload if available
-		if respPayload != nil {
-			fields = append(fields, zap.ByteString("response_payload", respPayload))
+		// Serialize response payload (if no error)
+		if resp != nil {
+			respSize := getPayloadSize(resp)
+			fields = append(fields, zap.Int("response_size_bytes", respSize))
+			if shouldLogPayload(info.FullMethod, respSize, config.MaxPayloadBytes) {
+				if respPayload := renderPayload(resp); respPayload != nil {
+					fields = append(fields, zap.ByteString("response_payload", respPayload))
+				}
+			}
 		}
 
 		// Log based on severity
@@ -97,8 +120,20 @@ func LoggingInterceptor(baseLogger *zap.Logger) grpc.UnaryServerInterceptor {
 	}
 }
 
+// sampled decides whether a successful request to method should be logged
+func (c LoggingConfig) sampled(method string) bool {
+	rate, ok := c.MethodSampleRates[method]
+	if !ok {
+		rate = c.SuccessSampleRate
+	}
+	if rate >= 1 {
+		return true
+	}
+	return rand.Float64() < rate
+}
+
 // shouldLogPayload determines if we should log the full payload based on method and size
-func shouldLogPayload(method string, payload interface{}) bool {
+func shouldLogPayload(method string, size, maxPayloadBytes int) bool {
 	// Skip payload logging for high-frequency methods
 	highFrequencyMethods := []string{
 		"/grpc.health.v1.Health/Check",
@@ -111,27 +146,26 @@ func shouldLogPayload(method string, payload interface{}) bool {
 		}
 	}
 
-	// Check payload size (rough estimation)
-	payloadStr := getPayloadSize(payload)
-	if len(payloadStr) > 1000 { // If estimated size > 1KB, skip full payload
-		return false
-	}
-
-	return true
+	return size <= maxPayloadBytes
 }
 
-// getPayloadSize returns a string representation of payload size
-func getPayloadSize(payload interface{}) string {
-	if payload == nil {
-		return "0B"
+// getPayloadSize returns the wire size of a protobuf payload in bytes
+func getPayloadSize(payload interface{}) int {
+	if msg, ok := payload.(proto.Message); ok {
+		return proto.Size(msg)
 	}
+	return 0
+}
 
-	// Quick size estimation without full marshaling
-	switch v := payload.(type) {
-	case string:
-		return fmt.Sprintf("%dB", len(v))
-	default:
-		// For other types, use a rough estimation
-		return "~unknown"
+// renderPayload renders a protobuf payload as redacted protojson, or nil if it is not a proto message
+func renderPayload(payload interface{}) []byte {
+	msg, ok := payload.(proto.Message)
+	if !ok {
+		return nil
+	}
+	data, err := logger.MarshalProto(msg)
+	if err != nil {
+		return nil
 	}
+	return data
 }
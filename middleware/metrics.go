@@ -1,95 +1,60 @@
 package middleware
 
 import (
-	"context"
 	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
-var (
-	// gRPC request metrics
-	grpcRequestsTotal = promauto.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "grpc_requests_total",
-			Help: "Total number of gRPC requests",
-		},
-		[]string{"method", "status_code"},
-	)
-
-	grpcRequestDuration = promauto.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "grpc_request_duration_seconds",
-			Help:    "Duration of gRPC requests in seconds",
-			Buckets: prometheus.DefBuckets,
-		},
-		[]string{"method", "status_code"},
-	)
-
-	grpcActiveConnections = promauto.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "grpc_active_connections",
-			Help: "Number of active gRPC connections",
-		},
-	)
-
-	// Rate limiting metrics
-	rateLimitExceeded = promauto.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "rate_limit_exceeded_total",
-			Help: "Total number of rate limit exceeded events",
-		},
-		[]string{"method", "key"},
-	)
-)
-
-// MetricsInterceptor collects Prometheus metrics for gRPC requests
-func MetricsInterceptor() grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		startTime := time.Now()
-
-		// Increment active connections
-		grpcActiveConnections.Inc()
-		defer grpcActiveConnections.Dec()
-
-		// Call the handler
-		resp, err := handler(ctx, req)
-
-		// Calculate duration
-		duration := time.Since(startTime)
-
-		// Determine gRPC status code
-		statusCode := codes.OK
-		if err != nil {
-			if st, ok := status.FromError(err); ok {
-				statusCode = st.Code()
-			} else {
-				statusCode = codes.Internal
-			}
-		}
-
-		// Record metrics
-		labels := prometheus.Labels{
-			"method":      info.FullMethod,
-			"status_code": strconv.Itoa(int(statusCode)),
-		}
-
-		grpcRequestsTotal.With(labels).Inc()
-		grpcRequestDuration.With(labels).Observe(duration.Seconds())
+// HTTPMetrics holds the HTTP gateway RED metrics
+type HTTPMetrics struct {
+	requestsTotal    *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	requestsInFlight prometheus.Gauge
+}
 
-		return resp, err
+// NewHTTPMetrics creates the HTTP gateway metrics and registers them with reg, which
+// defaults to prometheus.DefaultRegisterer
+func NewHTTPMetrics(reg prometheus.Registerer) *HTTPMetrics {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	factory := promauto.With(reg)
+
+	return &HTTPMetrics{
+		requestsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_requests_total",
+				Help: "Total number of HTTP requests",
+			},
+			[]string{"method", "route", "code"},
+		),
+		requestDuration: factory.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_request_duration_seconds",
+				Help:    "Duration of HTTP requests in seconds",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"method", "route", "code"},
+		),
+		requestsInFlight: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "http_requests_in_flight",
+				Help: "Number of HTTP requests currently being served",
+			},
+		),
 	}
 }
 
-// RecordRateLimitExceeded records rate limit exceeded events
-func RecordRateLimitExceeded(method, key string) {
-	rateLimitExceeded.With(prometheus.Labels{
+// RecordRequest records a completed HTTP request
+func (m *HTTPMetrics) RecordRequest(method, route string, code int, duration time.Duration) {
+	labels := prometheus.Labels{
 		"method": method,
-		"key":    key,
-	}).Inc()
+		"route":  route,
+		"code":   strconv.Itoa(code),
+	}
+	m.requestsTotal.With(labels).Inc()
+	m.requestDuration.With(labels).Observe(duration.Seconds())
 }
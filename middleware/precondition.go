@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/harryosmar/protobuf-go/etag"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const IfMatchHeader = "if-match"
+
+// etagField is the message field GatewayETagHeader reads
+const etagField = "etag"
+
+// IfMatchFromContext returns the If-Match condition of the request, from the if-match
+// metadata of gRPC callers or the If-Match header the gateway forwards with the
+// grpcgateway- prefix. Empty means unconditional.
+func IfMatchFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, key := range []string{IfMatchHeader, runtime.MetadataPrefix + IfMatchHeader} {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// GatewayETagHeader sets the ETag header from the etag field of the response or of one
+// of its message fields, e.g. GetUserResponse.user.etag.
+// Use with runtime.WithForwardResponseOption.
+func GatewayETagHeader(ctx context.Context, w http.ResponseWriter, msg proto.Message) error {
+	if tag := findETag(msg.ProtoReflect()); tag != "" {
+		w.Header().Set("ETag", etag.Quote(tag))
+	}
+	return nil
+}
+
+// findETag returns the etag string field of m or of its first populated message field
+// that has one
+func findETag(m protoreflect.Message) string {
+	fields := m.Descriptor().Fields()
+	if fd := fields.ByName(etagField); fd != nil && fd.Kind() == protoreflect.StringKind && fd.Cardinality() != protoreflect.Repeated {
+		return m.Get(fd).String()
+	}
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() || !m.Has(fd) {
+			continue
+		}
+		inner := m.Get(fd).Message()
+		if fd := inner.Descriptor().Fields().ByName(etagField); fd != nil && fd.Kind() == protoreflect.StringKind {
+			if tag := inner.Get(fd).String(); tag != "" {
+				return tag
+			}
+		}
+	}
+	return ""
+}
+
+// GatewayErrorHandler writes errors like runtime.DefaultHTTPErrorHandler, except that
+// FAILED_PRECONDITION on a request with If-Match is answered with 412 Precondition Failed.
+// Use with runtime.WithErrorHandler.
+func GatewayErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	if r.Header.Get("If-Match") != "" && status.Code(err) == codes.FailedPrecondition {
+		err = &runtime.HTTPStatusError{HTTPStatus: http.StatusPreconditionFailed, Err: err}
+	}
+	runtime.DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, err)
+}
+
+// HTTPConditionalGetMiddleware answers GET and HEAD requests whose If-None-Match matches
+// the ETag of a 200 response with 304 Not Modified and no body
+func HTTPConditionalGetMiddleware() HTTPMiddleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			condition := r.Header.Get("If-None-Match")
+			if condition == "" || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wroteHeader, notModified := false, false
+			writeHeader := func(next httpsnoop.WriteHeaderFunc, code int) {
+				if wroteHeader {
+					return
+				}
+				wroteHeader = true
+				header := w.Header()
+				if tag := header.Get("ETag"); code == http.StatusOK && tag != "" && etag.Match(condition, etag.Unquote(tag)) {
+					notModified = true
+					code = http.StatusNotModified
+					header.Del("Content-Type")
+					header.Del("Content-Length")
+				}
+				next(code)
+			}
+
+			next.ServeHTTP(httpsnoop.Wrap(w, httpsnoop.Hooks{
+				WriteHeader: func(next httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
+					return func(code int) { writeHeader(next, code) }
+				},
+				Write: func(next httpsnoop.WriteFunc) httpsnoop.WriteFunc {
+					return func(b []byte) (int, error) {
+						writeHeader(w.WriteHeader, http.StatusOK)
+						if notModified {
+							return len(b), nil
+						}
+						return next(b)
+					}
+				},
+			}), r)
+		})
+	}
+}
@@ -36,6 +36,8 @@ func MethodKeyExtractor(ctx context.Context, info *grpc.UnaryServerInfo) string
 type RateLimiter struct {
 	limiters map[string]*rate.Limiter
 	config   RateLimitConfig
+	strategy string // set by ApplyConfig
+	disabled bool   // set by ApplyConfig, lets requests through without limiting
 	mutex    sync.RWMutex
 }
 
@@ -57,6 +59,47 @@ func NewRateLimiter(config RateLimitConfig) *RateLimiter {
 	}
 }
 
+// NewConfiguredRateLimiter creates a rate limiter from the rate limit settings in cfg
+func NewConfiguredRateLimiter(cfg *config.Config) *RateLimiter {
+	rateLimiter := NewRateLimiter(RateLimitConfig{})
+	rateLimiter.ApplyConfig(cfg)
+	return rateLimiter
+}
+
+// ApplyConfig updates the enabled flag, strategy and limits from a configuration snapshot.
+// Existing limiters keep their tokens when only the limits change; a strategy change resets them.
+func (rl *RateLimiter) ApplyConfig(cfg *config.Config) error {
+	keyExtractor := DefaultKeyExtractor
+	if cfg.RateLimitStrategy == "per-method" {
+		keyExtractor = MethodKeyExtractor
+	}
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if rl.strategy != cfg.RateLimitStrategy {
+		rl.limiters = make(map[string]*rate.Limiter)
+	}
+	rl.strategy = cfg.RateLimitStrategy
+	rl.disabled = !cfg.RateLimitEnabled
+	rl.config.KeyExtractor = keyExtractor
+	rl.config.RequestsPerSecond = cfg.RateLimitRequestsPerSec
+	rl.config.BurstSize = cfg.RateLimitBurstSize
+
+	for _, limiter := range rl.limiters {
+		limiter.SetLimit(rate.Limit(rl.config.RequestsPerSecond))
+		limiter.SetBurst(rl.config.BurstSize)
+	}
+	return nil
+}
+
+// settings returns a consistent copy of the current configuration
+func (rl *RateLimiter) settings() (RateLimitConfig, bool) {
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
+	return rl.config, rl.disabled
+}
+
 // getLimiter gets or creates a rate limiter for the given key
 func (rl *RateLimiter) getLimiter(key string) *rate.Limiter {
 	rl.mutex.RLock()
@@ -82,11 +125,17 @@ func (rl *RateLimiter) getLimiter(key string) *rate.Limiter {
 	return limiter
 }
 
-// RateLimitInterceptor creates a gRPC interceptor for rate limiting
-func RateLimitInterceptor(rateLimiter *RateLimiter) grpc.UnaryServerInterceptor {
+// RateLimitInterceptor creates a gRPC interceptor for rate limiting, counting rejected
+// requests in metrics
+func RateLimitInterceptor(rateLimiter *RateLimiter, metrics *GRPCMetrics) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		config, disabled := rateLimiter.settings()
+		if disabled {
+			return handler(ctx, req)
+		}
+
 		// Extract rate limit key
-		key := rateLimiter.config.KeyExtractor(ctx, info)
+		key := config.KeyExtractor(ctx, info)
 
 		// Get rate limiter for this key
 		limiter := rateLimiter.getLimiter(key)
@@ -98,58 +147,20 @@ func RateLimitInterceptor(rateLimiter *RateLimiter) grpc.UnaryServerInterceptor
 			log.Warn("Rate limit exceeded",
 				zap.String("method", info.FullMethod),
 				zap.String("rate_limit_key", key),
-				zap.Int("requests_per_second", rateLimiter.config.RequestsPerSecond),
-				zap.Int("burst_size", rateLimiter.config.BurstSize),
+				zap.Int("requests_per_second", config.RequestsPerSecond),
+				zap.Int("burst_size", config.BurstSize),
 			)
 
 			// Record rate limit exceeded metric
-			RecordRateLimitExceeded(info.FullMethod, key)
+			metrics.RecordRateLimitExceeded(info.FullMethod, key)
 
 			// Return rate limit exceeded error
-			return nil, error2.ErrResourceExhausted.WithMessage(
-				"Rate limit exceeded. Maximum %d requests per second allowed.",
-				rateLimiter.config.RequestsPerSecond)
+			return nil, error2.ErrResourceExhausted.WithTemplate("rate_limit", error2.Params{
+				"limit": config.RequestsPerSecond,
+			})
 		}
 
 		// Request allowed, proceed to handler
 		return handler(ctx, req)
 	}
 }
-
-// NewGlobalRateLimitInterceptor creates a rate limiter with global limits
-func NewGlobalRateLimitInterceptor(requestsPerSecond, burstSize int) grpc.UnaryServerInterceptor {
-	config := RateLimitConfig{
-		RequestsPerSecond: requestsPerSecond,
-		BurstSize:         burstSize,
-		KeyExtractor:      DefaultKeyExtractor,
-	}
-	rateLimiter := NewRateLimiter(config)
-	return RateLimitInterceptor(rateLimiter)
-}
-
-// NewPerMethodRateLimitInterceptor creates a rate limiter with per-method limits
-func NewPerMethodRateLimitInterceptor(requestsPerSecond, burstSize int) grpc.UnaryServerInterceptor {
-	config := RateLimitConfig{
-		RequestsPerSecond: requestsPerSecond,
-		BurstSize:         burstSize,
-		KeyExtractor:      MethodKeyExtractor,
-	}
-	rateLimiter := NewRateLimiter(config)
-	return RateLimitInterceptor(rateLimiter)
-}
-
-func NewRateLimitInterceptors(cfg *config.Config) []grpc.UnaryServerInterceptor {
-	if !cfg.RateLimitEnabled {
-		return []grpc.UnaryServerInterceptor{}
-	}
-
-	if cfg.RateLimitStrategy == "per-method" {
-		return []grpc.UnaryServerInterceptor{
-			NewPerMethodRateLimitInterceptor(cfg.RateLimitRequestsPerSec, cfg.RateLimitBurstSize),
-		}
-	}
-
-	return []grpc.UnaryServerInterceptor{
-		NewGlobalRateLimitInterceptor(cfg.RateLimitRequestsPerSec, cfg.RateLimitBurstSize),
-	}
-}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+	error2 "github.com/harryosmar/protobuf-go/error"
+	"github.com/harryosmar/protobuf-go/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// CrashReport is the structured post-mortem record written for each recovered panic
+type CrashReport struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Panic      string    `json:"panic"`
+	Stack      string    `json:"stack"`
+	GoVersion  string    `json:"go_version"`
+	Goroutines int       `json:"goroutines"`
+}
+
+// RecoveryInterceptor converts panics in downstream interceptors and handlers into ErrInternalServer,
+// counting them in metrics. When crashDumpDir is not empty a JSON crash report is written there
+// for each panic.
+func RecoveryInterceptor(crashDumpDir string, metrics *GRPCMetrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = handlePanic(ctx, info.FullMethod, r, crashDumpDir, metrics)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// RecoveryStreamInterceptor converts panics in streaming handlers into ErrInternalServer
+func RecoveryStreamInterceptor(crashDumpDir string, metrics *GRPCMetrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = handlePanic(ss.Context(), info.FullMethod, r, crashDumpDir, metrics)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// handlePanic logs and records a recovered panic and returns the error sent to the client
+func handlePanic(ctx context.Context, method string, recovered interface{}, crashDumpDir string, metrics *GRPCMetrics) error {
+	report := CrashReport{
+		Time:       time.Now().UTC(),
+		Method:     method,
+		RequestID:  GetRequestID(ctx),
+		Panic:      fmt.Sprint(recovered),
+		Stack:      string(debug.Stack()),
+		GoVersion:  runtime.Version(),
+		Goroutines: runtime.NumGoroutine(),
+	}
+
+	log := logger.FromContext(ctx)
+	log.Error("Recovered from panic in gRPC handler",
+		zap.String("method", report.Method),
+		zap.String("request_id", report.RequestID),
+		zap.String("panic", report.Panic),
+		zap.String("stack", report.Stack),
+	)
+
+	metrics.RecordPanic(method)
+
+	if crashDumpDir != "" {
+		path, err := writeCrashReport(crashDumpDir, report)
+		if err != nil {
+			log.Error("Failed to write crash report", zap.String("dir", crashDumpDir), zap.Error(err))
+		} else {
+			log.Info("Crash report written", zap.String("path", path))
+		}
+	}
+
+	// Error conversion interceptor may not have run, so return a gRPC status directly
+	return error2.ErrInternalServer.ToLocalizedGRPCStatus(LocaleFromContext(ctx))
+}
+
+// writeCrashReport writes report as JSON into dir, under a name made of the time and a
+// random suffix, and returns the file path
+func writeCrashReport(dir string, report CrashReport) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	// The request ID comes from the client, so it stays in the report and out of the path
+	name := fmt.Sprintf("crash-%s-%s.json", report.Time.Format("20060102T150405.000000000"), uuid.New().String())
+	path := filepath.Join(dir, name)
+
+	return path, os.WriteFile(path, data, 0o600)
+}
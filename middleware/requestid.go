@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"regexp"
 
 	"github.com/google/uuid"
 	"github.com/harryosmar/protobuf-go/logger"
@@ -10,12 +11,16 @@ import (
 	"google.golang.org/grpc/metadata"
 )
 
-const (
-	RequestIDHeader     = "x-request-id"
-	RequestIDContextKey = "request-id"
-)
+const RequestIDHeader = "x-request-id"
+
+// validRequestID matches the client request IDs that are kept, such as UUIDs; others are
+// replaced so that clients cannot inject arbitrary text into logs and file names
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+
+// requestIDContextKey is unexported so the key cannot collide with values set by other packages
+type requestIDContextKey struct{}
 
-// RequestIDInterceptor adds a request ID to gRPC requests if not present and injects logger with request ID
+// RequestIDInterceptor adds a request ID to gRPC requests if not present or invalid and injects logger with request ID
 func RequestIDInterceptor(baseLogger *zap.Logger) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		// Get metadata from context
@@ -28,8 +33,8 @@ func RequestIDInterceptor(baseLogger *zap.Logger) grpc.UnaryServerInterceptor {
 		requestIDs := md.Get(RequestIDHeader)
 		var requestID string
 
-		if len(requestIDs) == 0 {
-			// Generate new UUID if not present
+		if len(requestIDs) == 0 || !validRequestID.MatchString(requestIDs[0]) {
+			// Generate new UUID if not present or invalid
 			requestID = uuid.New().String()
 			md.Set(RequestIDHeader, requestID)
 			// Update context with new metadata
@@ -39,7 +44,7 @@ func RequestIDInterceptor(baseLogger *zap.Logger) grpc.UnaryServerInterceptor {
 		}
 
 		// Store request ID in context for service access
-		ctx = context.WithValue(ctx, RequestIDContextKey, requestID)
+		ctx = context.WithValue(ctx, requestIDContextKey{}, requestID)
 
 		// Create logger with request ID and add to context
 		requestLogger := logger.WithRequestID(baseLogger, requestID)
@@ -61,7 +66,7 @@ func RequestIDInterceptor(baseLogger *zap.Logger) grpc.UnaryServerInterceptor {
 
 // GetRequestID extracts the request ID from context
 func GetRequestID(ctx context.Context) string {
-	if requestID, ok := ctx.Value(RequestIDContextKey).(string); ok {
+	if requestID, ok := ctx.Value(requestIDContextKey{}).(string); ok {
 		return requestID
 	}
 	return ""
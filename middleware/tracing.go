@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/harryosmar/protobuf-go/logger"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// TraceLogInterceptor adds trace_id and span_id of the current span to the context logger.
+// It must run after RequestIDInterceptor; spans are started by the otelgrpc stats handler.
+func TraceLogInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+			ctx = logger.WithFields(ctx,
+				zap.String("trace_id", spanCtx.TraceID().String()),
+				zap.String("span_id", spanCtx.SpanID().String()),
+			)
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,36 @@
+package modules
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/harryosmar/protobuf-go/app"
+	hellopb "github.com/harryosmar/protobuf-go/gen/hello"
+	"github.com/harryosmar/protobuf-go/service"
+	"google.golang.org/grpc"
+)
+
+// HelloModule serves HelloService
+type HelloModule struct {
+	app.BaseModule
+}
+
+// NewHelloModule creates the hello module
+func NewHelloModule() *HelloModule {
+	return &HelloModule{}
+}
+
+// Name implements app.Module
+func (m *HelloModule) Name() string {
+	return "hello"
+}
+
+// RegisterGRPC implements app.Module
+func (m *HelloModule) RegisterGRPC(registrar grpc.ServiceRegistrar) {
+	hellopb.RegisterHelloServiceServer(registrar, service.NewHelloServiceServer())
+}
+
+// RegisterGateway implements app.Module
+func (m *HelloModule) RegisterGateway(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) error {
+	return hellopb.RegisterHelloServiceHandlerFromEndpoint(ctx, mux, endpoint, opts)
+}
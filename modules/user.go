@@ -0,0 +1,123 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/harryosmar/protobuf-go/app"
+	"github.com/harryosmar/protobuf-go/cache"
+	"github.com/harryosmar/protobuf-go/config"
+	userpb "github.com/harryosmar/protobuf-go/gen/user"
+	"github.com/harryosmar/protobuf-go/logger"
+	"github.com/harryosmar/protobuf-go/repository"
+	"github.com/harryosmar/protobuf-go/secrets"
+	"github.com/harryosmar/protobuf-go/service"
+	"github.com/harryosmar/protobuf-go/usecase"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+)
+
+// UserModule serves UserService backed by the shared database, or by process memory with
+// STORAGE_BACKEND=memory, cached as configured by
+// USER_CACHE_BACKEND, and runs the job that purges deleted users after
+// USER_PURGE_RETENTION
+type UserModule struct {
+	app.BaseModule
+	userUsecase  usecase.UserUsecase
+	cacheBackend cache.Backend // nil when caching is disabled
+	stopPurge    context.CancelFunc
+	purgeDone    sync.WaitGroup
+}
+
+// NewUserModule creates the user module
+func NewUserModule() *UserModule {
+	return &UserModule{}
+}
+
+// Name implements app.Module
+func (m *UserModule) Name() string {
+	return "user"
+}
+
+// Migrate migrates the users table from the generated GORM model and its email index
+func (m *UserModule) Migrate(db *gorm.DB) error {
+	return repository.MigrateUsers(db)
+}
+
+// Init wires the repository, its cache and the usecase and starts the purge job
+func (m *UserModule) Init(deps app.Deps) error {
+	userRepo := newUserRepository(deps)
+	backend, err := newUserCacheBackend(deps.Config)
+	if err != nil {
+		return err
+	}
+	if backend != nil {
+		m.cacheBackend = backend
+		userRepo = repository.NewCachedUserRepository(userRepo, backend, repository.CacheOptions{
+			TTL:         deps.Config.UserCacheTTL,
+			NegativeTTL: deps.Config.UserCacheNegativeTTL,
+			KeyPrefix:   deps.Config.UserCacheKeyPrefix,
+			Metrics:     deps.CacheMetrics,
+		})
+	}
+	m.userUsecase = usecase.NewUserUsecase(userRepo)
+
+	purgeJob := usecase.NewUserPurgeJob(m.userUsecase, deps.Config.UserPurgeRetention, deps.Config.UserPurgeBatchSize)
+	ctx, cancel := context.WithCancel(logger.ToContext(context.Background(), deps.Logger.Named("user_purge")))
+	m.stopPurge = cancel
+	m.purgeDone.Add(1)
+	go func() {
+		defer m.purgeDone.Done()
+		purgeJob.Run(ctx, deps.Config.UserPurgeInterval)
+	}()
+	return nil
+}
+
+// RegisterGRPC implements app.Module
+func (m *UserModule) RegisterGRPC(registrar grpc.ServiceRegistrar) {
+	userpb.RegisterUserServiceServer(registrar, service.NewUserServiceServer(m.userUsecase))
+}
+
+// RegisterGateway implements app.Module
+func (m *UserModule) RegisterGateway(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) error {
+	return userpb.RegisterUserServiceHandlerFromEndpoint(ctx, mux, endpoint, opts)
+}
+
+// Shutdown stops the purge job, waiting for a running purge to notice the cancellation,
+// and closes the cache backend
+func (m *UserModule) Shutdown(ctx context.Context) error {
+	if m.stopPurge != nil {
+		m.stopPurge()
+	}
+	m.purgeDone.Wait()
+	if closer, ok := m.cacheBackend.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// newUserRepository creates the repository selected by STORAGE_BACKEND
+func newUserRepository(deps app.Deps) repository.UserRepository {
+	if deps.Config.StorageBackend == "memory" {
+		return repository.NewUserRepositoryMemory()
+	}
+	return repository.NewUserRepositoryMySQL(deps.DB)
+}
+
+// newUserCacheBackend creates the backend selected by USER_CACHE_BACKEND, nil for none
+func newUserCacheBackend(cfg *config.Config) (cache.Backend, error) {
+	switch cfg.UserCacheBackend {
+	case "memory":
+		return cache.NewLRU(cfg.UserCacheSize), nil
+	case "redis":
+		redisURL, err := secrets.NewResolver(cfg.SecretsVaultFile).Resolve(context.Background(), cfg.UserCacheRedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve USER_CACHE_REDIS_URL: %w", err)
+		}
+		return cache.NewRedis(redisURL, cfg.UserCacheRedisPoolSize)
+	}
+	return nil, nil
+}
@@ -0,0 +1,512 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	appErrors "github.com/harryosmar/protobuf-go/error"
+	"github.com/harryosmar/protobuf-go/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// mysqlDuplicateEntry is the MySQL error number for unique key violations
+const mysqlDuplicateEntry = 1062
+
+// Page size bounds for List
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 1000
+)
+
+// CRUDRepository is the data access every entity gets from Repository. Reads and updates
+// take optional columns to restrict them to; none means every column.
+type CRUDRepository[ORM any] interface {
+	Create(ctx context.Context, row *ORM) error
+	GetByID(ctx context.Context, id int64, columns ...string) (*ORM, error)
+	Update(ctx context.Context, row *ORM, columns ...string) error
+	Delete(ctx context.Context, id int64) error
+	List(ctx context.Context, opts ListOptions) (*Page[ORM], error)
+}
+
+// SoftDeleteRepository is the data access of entities with a gorm.DeletedAt field. Their
+// Delete only marks rows as deleted, and reads skip deleted rows unless asked for them.
+type SoftDeleteRepository[ORM any] interface {
+	CRUDRepository[ORM]
+	GetByIDWithDeleted(ctx context.Context, id int64, columns ...string) (*ORM, error)
+	Restore(ctx context.Context, id int64) error
+	Purge(ctx context.Context, deletedBefore time.Time, limit int) (int64, error)
+}
+
+// ListOptions selects one page of List, ordered by primary key
+type ListOptions struct {
+	PageSize       int      // defaults to DefaultPageSize, capped at MaxPageSize
+	PageToken      string   // NextPageToken of the previous page, empty for the first page
+	IncludeDeleted bool     // also list soft-deleted rows
+	Columns        []string // columns to read besides the primary key, all when empty
+}
+
+// Page is one page of rows
+type Page[T any] struct {
+	Items         []*T
+	NextPageToken string // empty on the last page
+}
+
+// ErrorCodes are the application errors a repository returns; zero values fall back
+// to ErrAlreadyExists and ErrInternalServer
+type ErrorCodes struct {
+	Exists         appErrors.CodeErr // unique key violation on create or update
+	CreationFailed appErrors.CodeErr
+	UpdateFailed   appErrors.CodeErr
+	DeletionFailed appErrors.CodeErr
+}
+
+// versionColumn is the integer column that makes an entity versioned
+const versionColumn = "version"
+
+// Repository implements CRUDRepository for a protoc-gen-gorm ORM type and converts rows
+// to and from its protobuf message PB with the generated ToPB/ToORM methods. When ORM has
+// a gorm.DeletedAt field it also implements SoftDeleteRepository. When ORM has an integer
+// version column, rows start at version 1 and Update is an optimistic compare-and-swap on
+// it. Entity repositories embed it and add their own lookups.
+type Repository[ORM any, PB any] struct {
+	db         *gorm.DB
+	codes      ErrorCodes
+	table      string
+	primaryKey *schema.Field
+	deletedAt  *schema.Field // nil when ORM is not soft-deletable
+	version    *schema.Field // nil when ORM is not versioned
+	columns    map[string]bool
+	autoUpdate []string // columns stamped on every update, such as updated_at
+}
+
+// toPB is implemented by *ORM
+type toPB[PB any] interface {
+	ToPB(ctx context.Context) (PB, error)
+}
+
+// toORM is implemented by *PB
+type toORM[ORM any] interface {
+	ToORM(ctx context.Context) (ORM, error)
+}
+
+// NewRepository creates a repository for ORM. It panics when ORM and PB are not a
+// generated ORM/protobuf pair or ORM has no primary key, which are programming errors.
+func NewRepository[ORM any, PB any](db *gorm.DB, codes ErrorCodes) *Repository[ORM, PB] {
+	if _, ok := any(new(ORM)).(toPB[PB]); !ok {
+		panic(fmt.Sprintf("repository: %T has no ToPB method returning %T", new(ORM), *new(PB)))
+	}
+	if _, ok := any(new(PB)).(toORM[ORM]); !ok {
+		panic(fmt.Sprintf("repository: %T has no ToORM method returning %T", new(PB), *new(ORM)))
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(ORM)); err != nil {
+		panic(fmt.Sprintf("repository: cannot parse %T: %v", new(ORM), err))
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		panic(fmt.Sprintf("repository: %T has no primary key", new(ORM)))
+	}
+
+	if codes.Exists == 0 {
+		codes.Exists = appErrors.ErrAlreadyExists
+	}
+	for _, code := range []*appErrors.CodeErr{&codes.CreationFailed, &codes.UpdateFailed, &codes.DeletionFailed} {
+		if *code == 0 {
+			*code = appErrors.ErrInternalServer
+		}
+	}
+
+	repo := &Repository[ORM, PB]{
+		db:         db,
+		codes:      codes,
+		table:      stmt.Schema.Table,
+		primaryKey: stmt.Schema.PrioritizedPrimaryField,
+		columns:    make(map[string]bool, len(stmt.Schema.DBNames)),
+	}
+	for _, column := range stmt.Schema.DBNames {
+		repo.columns[column] = true
+	}
+	for _, field := range stmt.Schema.Fields {
+		if field.AutoUpdateTime > 0 {
+			repo.autoUpdate = append(repo.autoUpdate, field.DBName)
+		}
+		switch {
+		case field.FieldType == reflect.TypeOf(gorm.DeletedAt{}):
+			repo.deletedAt = field
+		case field.DBName == versionColumn && (field.DataType == schema.Int || field.DataType == schema.Uint):
+			repo.version = field
+		}
+	}
+	return repo
+}
+
+// DB returns the database handle for queries the generic methods do not cover
+func (r *Repository[ORM, PB]) DB(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx)
+}
+
+// Create inserts row and fills in its generated primary key; versioned rows start at
+// version 1
+func (r *Repository[ORM, PB]) Create(ctx context.Context, row *ORM) error {
+	if r.version != nil {
+		if err := r.version.Set(ctx, reflect.ValueOf(row).Elem(), 1); err != nil {
+			return r.codes.CreationFailed.Wrap(err)
+		}
+	}
+	if err := r.db.WithContext(ctx).Create(row).Error; err != nil {
+		return r.TranslateError(err, r.codes.CreationFailed)
+	}
+	return nil
+}
+
+// GetByID retrieves a row by primary key, reading only columns (and the primary key)
+// when given
+func (r *Repository[ORM, PB]) GetByID(ctx context.Context, id int64, columns ...string) (*ORM, error) {
+	db, err := r.selectColumns(r.db.WithContext(ctx), columns)
+	if err != nil {
+		return nil, err
+	}
+	return r.first(db, r.primaryKey.DBName, id)
+}
+
+// GetByIDWithDeleted retrieves a row by primary key even if it is soft-deleted
+func (r *Repository[ORM, PB]) GetByIDWithDeleted(ctx context.Context, id int64, columns ...string) (*ORM, error) {
+	db, err := r.selectColumns(r.db.WithContext(ctx).Unscoped(), columns)
+	if err != nil {
+		return nil, err
+	}
+	return r.first(db, r.primaryKey.DBName, id)
+}
+
+// GetBy retrieves the row whose column equals value, for columns with a unique index.
+// column must come from code, never from client input.
+func (r *Repository[ORM, PB]) GetBy(ctx context.Context, column string, value any) (*ORM, error) {
+	return r.first(r.db.WithContext(ctx), column, value)
+}
+
+// first returns the first row matching column = value; not found is not an error at
+// repository level and returns nil
+func (r *Repository[ORM, PB]) first(db *gorm.DB, column string, value any) (*ORM, error) {
+	ctx := db.Statement.Context
+	var row ORM
+	err := db.
+		Where(clause.Eq{Column: clause.Column{Name: column}, Value: value}).
+		First(&row).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.FromContext(ctx).Debug("Lookup returned no rows", zap.String("table", r.table), zap.String("column", column))
+			return nil, nil
+		}
+		return nil, r.TranslateError(err, appErrors.ErrInternalServer)
+	}
+	return &row, nil
+}
+
+// Update saves every column of row, or only columns when given; the version and
+// automatically stamped columns such as updated_at are always saved. A versioned row is
+// only saved while the stored version still equals row's, which is then incremented; if
+// another update came first (or the row was deleted) nothing is saved and it fails with
+// ErrAborted, and the caller should read the row again before retrying.
+func (r *Repository[ORM, PB]) Update(ctx context.Context, row *ORM, columns ...string) error {
+	var selected any = "*"
+	if len(columns) > 0 {
+		if err := r.checkColumns(columns); err != nil {
+			return err
+		}
+		required := r.autoUpdate
+		if r.version != nil {
+			required = append([]string{r.version.DBName}, required...)
+		}
+		selected = withColumns(required, columns)
+	}
+
+	if r.version == nil {
+		db := r.db.WithContext(ctx)
+		if len(columns) == 0 {
+			db = db.Save(row)
+		} else {
+			db = db.Model(row).Select(selected).Updates(row)
+		}
+		if err := db.Error; err != nil {
+			return r.TranslateError(err, r.codes.UpdateFailed)
+		}
+		return nil
+	}
+
+	value := reflect.ValueOf(row).Elem()
+	current, _ := r.version.ValueOf(ctx, value)
+	expected := toInt64(current)
+	if err := r.version.Set(ctx, value, expected+1); err != nil {
+		return r.codes.UpdateFailed.Wrap(err)
+	}
+
+	result := r.db.WithContext(ctx).Model(row).
+		Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: r.version.DBName}, Value: expected}).
+		Select(selected).
+		Updates(row)
+	if result.Error != nil || result.RowsAffected == 0 {
+		// Leave the row as the caller passed it
+		_ = r.version.Set(ctx, value, expected)
+	}
+	if result.Error != nil {
+		return r.TranslateError(result.Error, r.codes.UpdateFailed)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.ErrAborted.WithTemplate("version_conflict", appErrors.Params{"version": expected})
+	}
+	return nil
+}
+
+// Delete deletes a row by primary key; soft-deletable rows are only marked as deleted
+func (r *Repository[ORM, PB]) Delete(ctx context.Context, id int64) error {
+	result := r.db.WithContext(ctx).Delete(new(ORM), id)
+	if result.Error != nil {
+		return r.TranslateError(result.Error, r.codes.DeletionFailed)
+	}
+	// Return success even if no rows affected - idempotent delete
+	return nil
+}
+
+// Restore clears the deletion mark of a soft-deleted row, incrementing the version of
+// versioned rows. Restoring a row that is not
+// deleted or does not exist is a no-op; a row whose unique keys were taken meanwhile
+// fails with the Exists code.
+func (r *Repository[ORM, PB]) Restore(ctx context.Context, id int64) error {
+	if err := r.requireSoftDelete(); err != nil {
+		return err
+	}
+	columns := map[string]any{r.deletedAt.DBName: nil}
+	if r.version != nil {
+		columns[r.version.DBName] = gorm.Expr("? + 1", clause.Column{Name: r.version.DBName})
+	}
+	err := r.db.WithContext(ctx).Unscoped().Model(new(ORM)).
+		Where(clause.Eq{Column: clause.Column{Name: r.primaryKey.DBName}, Value: id}).
+		Where(clause.Neq{Column: clause.Column{Name: r.deletedAt.DBName}, Value: nil}).
+		Updates(columns).Error
+	if err != nil {
+		return r.TranslateError(err, r.codes.UpdateFailed)
+	}
+	return nil
+}
+
+// Purge permanently deletes up to limit rows soft-deleted before deletedBefore, oldest
+// primary keys first, and returns how many were deleted. Callers repeat it until fewer
+// than limit rows are deleted, keeping each statement's locks short.
+func (r *Repository[ORM, PB]) Purge(ctx context.Context, deletedBefore time.Time, limit int) (int64, error) {
+	if err := r.requireSoftDelete(); err != nil {
+		return 0, err
+	}
+	result := r.db.WithContext(ctx).Unscoped().
+		Where(clause.Lt{Column: clause.Column{Name: r.deletedAt.DBName}, Value: deletedBefore}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: r.primaryKey.DBName}}).
+		Limit(limit).
+		Delete(new(ORM))
+	if result.Error != nil {
+		return 0, r.TranslateError(result.Error, r.codes.DeletionFailed)
+	}
+	return result.RowsAffected, nil
+}
+
+// requireSoftDelete fails the soft delete methods of entities without a gorm.DeletedAt field
+func (r *Repository[ORM, PB]) requireSoftDelete() error {
+	if r.deletedAt == nil {
+		return appErrors.ErrUnimplemented.WithMessage("%s has no gorm.DeletedAt field", r.table)
+	}
+	return nil
+}
+
+// List returns one page of rows ordered by primary key. Pages are keyed on the last
+// primary key rather than an offset, so concurrent inserts do not shift them.
+func (r *Repository[ORM, PB]) List(ctx context.Context, opts ListOptions) (*Page[ORM], error) {
+	size := PageSize(opts.PageSize)
+	column := clause.Column{Name: r.primaryKey.DBName}
+
+	query, err := r.selectColumns(r.db.WithContext(ctx), opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+	query = query.Order(clause.OrderByColumn{Column: column}).Limit(size + 1)
+	if opts.IncludeDeleted {
+		query = query.Unscoped()
+	}
+	if opts.PageToken != "" {
+		after, err := DecodePageToken(opts.PageToken)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(clause.Gt{Column: column, Value: after})
+	}
+
+	var rows []*ORM
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, r.TranslateError(err, appErrors.ErrInternalServer)
+	}
+
+	page := &Page[ORM]{Items: rows}
+	if len(rows) > size {
+		page.Items = rows[:size]
+		last, _ := r.primaryKey.ValueOf(ctx, reflect.ValueOf(page.Items[size-1]).Elem())
+		page.NextPageToken = EncodePageToken(toInt64(last))
+	}
+	return page, nil
+}
+
+// selectColumns restricts a read to columns and the primary key, which pagination and
+// callers rely on; no columns reads every column
+func (r *Repository[ORM, PB]) selectColumns(db *gorm.DB, columns []string) (*gorm.DB, error) {
+	if len(columns) == 0 {
+		return db, nil
+	}
+	if err := r.checkColumns(columns); err != nil {
+		return nil, err
+	}
+	return db.Select(withColumns([]string{r.primaryKey.DBName}, columns)), nil
+}
+
+// checkColumns fails with ErrInvalidArgument when a column does not exist, so that
+// column names never reach the query unchecked
+func (r *Repository[ORM, PB]) checkColumns(columns []string) error {
+	for _, column := range columns {
+		if !r.columns[column] {
+			return appErrors.ErrInvalidArgument.WithTemplate("validation", appErrors.Params{
+				"reason": fmt.Sprintf("%s has no column %q", r.table, column),
+			})
+		}
+	}
+	return nil
+}
+
+// ToPB converts a row to its protobuf message
+func (r *Repository[ORM, PB]) ToPB(ctx context.Context, row *ORM) (*PB, error) {
+	message, err := any(row).(toPB[PB]).ToPB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// ToPBList converts rows, such as the items of a Page, to protobuf messages
+func (r *Repository[ORM, PB]) ToPBList(ctx context.Context, rows []*ORM) ([]*PB, error) {
+	messages := make([]*PB, 0, len(rows))
+	for _, row := range rows {
+		message, err := r.ToPB(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// ToORM converts a protobuf message to its row
+func (r *Repository[ORM, PB]) ToORM(ctx context.Context, message *PB) (*ORM, error) {
+	row, err := any(message).(toORM[ORM]).ToORM(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// TranslateError maps a database error to an application error: unique key violations
+// become the Exists code, cancellations and timeouts keep their meaning, and everything
+// else is wrapped in fallback
+func (r *Repository[ORM, PB]) TranslateError(err error, fallback appErrors.CodeErr) error {
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry:
+		return r.codes.Exists.Wrap(&duplicateKeyError{key: duplicateKeyName(mysqlErr.Message), err: err})
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return r.codes.Exists.Wrap(err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return appErrors.ErrDeadlineExceeded.Wrap(err)
+	case errors.Is(err, context.Canceled):
+		return appErrors.ErrCancelled.Wrap(err)
+	}
+	return fallback.Wrap(err)
+}
+
+// duplicateKeyError is the cause of a unique key violation. MySQL quotes the duplicated
+// value, such as an email, so only the key name is kept for logs.
+type duplicateKeyError struct {
+	key string
+	err error
+}
+
+func (e *duplicateKeyError) Error() string {
+	return "duplicate entry for " + e.key
+}
+
+func (e *duplicateKeyError) Unwrap() error {
+	return e.err
+}
+
+// duplicateKeyName extracts the key name from "Duplicate entry '<value>' for key '<key>'"
+func duplicateKeyName(message string) string {
+	i := strings.LastIndex(message, " for key ")
+	if i < 0 {
+		return "unknown key"
+	}
+	return strings.Trim(message[i+len(" for key "):], "'")
+}
+
+// PageSize applies the List defaults and bounds to a requested page size
+func PageSize(requested int) int {
+	switch {
+	case requested <= 0:
+		return DefaultPageSize
+	case requested > MaxPageSize:
+		return MaxPageSize
+	}
+	return requested
+}
+
+// EncodePageToken returns the opaque token of the page after the row with primary key id
+func EncodePageToken(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+// DecodePageToken returns the primary key a page token continues after
+func DecodePageToken(token string) (int64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		var id int64
+		if id, err = strconv.ParseInt(string(data), 10, 64); err == nil {
+			return id, nil
+		}
+	}
+	return 0, appErrors.ErrInvalidArgument.WithTemplate("validation", appErrors.Params{"reason": "invalid page token"})
+}
+
+// withColumns returns required followed by the columns not already in it
+func withColumns(required, columns []string) []string {
+	all := append([]string(nil), required...)
+	for _, column := range columns {
+		if !slices.Contains(all, column) {
+			all = append(all, column)
+		}
+	}
+	return all
+}
+
+// toInt64 converts an integer primary key value
+func toInt64(value any) int64 {
+	v := reflect.ValueOf(value)
+	switch {
+	case v.CanInt():
+		return v.Int()
+	case v.CanUint():
+		return int64(v.Uint())
+	}
+	return 0
+}
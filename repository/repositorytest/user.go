@@ -0,0 +1,429 @@
+// Package repositorytest holds the conformance suites every implementation of a
+// repository interface must pass, so that tests and local development on one
+// implementation hold for the others. Run a suite from the implementation's tests:
+//
+//	func TestUserRepositoryMemory(t *testing.T) {
+//		repositorytest.TestUserRepository(t, func(t *testing.T) repository.UserRepository {
+//			return repository.NewUserRepositoryMemory()
+//		})
+//	}
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	appErrors "github.com/harryosmar/protobuf-go/error"
+	userpb "github.com/harryosmar/protobuf-go/gen/user"
+	"github.com/harryosmar/protobuf-go/repository"
+)
+
+// clockSkew is how far timestamps set by the repository, possibly by a database server
+// storing milliseconds, may differ from the test's clock
+const clockSkew = 2 * time.Second
+
+// TestUserRepository runs the UserRepository conformance suite. newRepo is called by
+// every subtest and must return an empty repository; implementations backed by a shared
+// database truncate the users table there.
+func TestUserRepository(t *testing.T, newRepo func(t *testing.T) repository.UserRepository) {
+	ctx := context.Background()
+
+	t.Run("CreateAssignsIncreasingIDs", func(t *testing.T) {
+		repo := newRepo(t)
+		var last uint32
+		for i := range 3 {
+			user := createUser(t, repo, fmt.Sprintf("user%d@example.com", i))
+			if user.Id <= last {
+				t.Fatalf("Create assigned id %d after %d", user.Id, last)
+			}
+			last = user.Id
+			if user.Version != 1 {
+				t.Errorf("Create set version %d, want 1", user.Version)
+			}
+			checkRecent(t, "created_at", user.CreatedAt)
+			checkRecent(t, "updated_at", user.UpdatedAt)
+		}
+	})
+
+	t.Run("CreateDuplicateEmail", func(t *testing.T) {
+		repo := newRepo(t)
+		createUser(t, repo, "taken@example.com")
+		err := repo.Create(ctx, &userpb.UserRecordORM{Name: "Other", Email: "taken@example.com"})
+		checkCode(t, "Create", err, appErrors.ErrUserEmailExists)
+	})
+
+	t.Run("NotFoundReturnsNil", func(t *testing.T) {
+		repo := newRepo(t)
+		if user, err := repo.GetByID(ctx, 42); user != nil || err != nil {
+			t.Errorf("GetByID of a missing user = %v, %v; want nil, nil", user, err)
+		}
+		if user, err := repo.GetByIDWithDeleted(ctx, 42); user != nil || err != nil {
+			t.Errorf("GetByIDWithDeleted of a missing user = %v, %v; want nil, nil", user, err)
+		}
+		if user, err := repo.GetByEmail(ctx, "missing@example.com"); user != nil || err != nil {
+			t.Errorf("GetByEmail of a missing user = %v, %v; want nil, nil", user, err)
+		}
+	})
+
+	t.Run("GetReturnsCreated", func(t *testing.T) {
+		repo := newRepo(t)
+		created := createUser(t, repo, "ada@example.com")
+
+		byID := mustGet(t, repo, created.Id)
+		checkSame(t, byID, created)
+		byEmail, err := repo.GetByEmail(ctx, "ada@example.com")
+		if err != nil || byEmail == nil {
+			t.Fatalf("GetByEmail = %v, %v; want the user", byEmail, err)
+		}
+		checkSame(t, byEmail, created)
+
+		byID.Name = "Changed"
+		if again := mustGet(t, repo, created.Id); again.Name != created.Name {
+			t.Error("modifying a returned user changed the stored user")
+		}
+	})
+
+	t.Run("GetByIDColumns", func(t *testing.T) {
+		repo := newRepo(t)
+		created := createUser(t, repo, "ada@example.com")
+
+		user, err := repo.GetByID(ctx, int64(created.Id), "name")
+		if err != nil || user == nil {
+			t.Fatalf("GetByID with columns = %v, %v; want the user", user, err)
+		}
+		// Columns that were not asked for may or may not be read
+		if user.Id != created.Id || user.Name != created.Name {
+			t.Errorf("GetByID with columns = %+v; want id %d and name %q", user, created.Id, created.Name)
+		}
+	})
+
+	t.Run("UpdateIncrementsVersion", func(t *testing.T) {
+		repo := newRepo(t)
+		user := createUser(t, repo, "ada@example.com")
+		before := user.UpdatedAt
+
+		user.Name = "Ada Lovelace"
+		if err := repo.Update(ctx, user); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if user.Version != 2 {
+			t.Errorf("Update left version %d, want 2", user.Version)
+		}
+		stored := mustGet(t, repo, user.Id)
+		if stored.Name != "Ada Lovelace" || stored.Version != 2 {
+			t.Errorf("stored user = %+v; want name %q and version 2", stored, "Ada Lovelace")
+		}
+		if stored.UpdatedAt.Before(before.Add(-clockSkew)) {
+			t.Errorf("Update moved updated_at back from %v to %v", before, stored.UpdatedAt)
+		}
+	})
+
+	t.Run("UpdateStaleVersion", func(t *testing.T) {
+		repo := newRepo(t)
+		user := createUser(t, repo, "ada@example.com")
+		stale := *user
+
+		user.Name = "First"
+		if err := repo.Update(ctx, user); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		stale.Name = "Second"
+		checkCode(t, "Update of a stale version", repo.Update(ctx, &stale), appErrors.ErrAborted)
+		if stale.Version != 1 {
+			t.Errorf("failed Update changed the version to %d, want 1", stale.Version)
+		}
+		if stored := mustGet(t, repo, user.Id); stored.Name != "First" {
+			t.Errorf("stale Update saved name %q", stored.Name)
+		}
+	})
+
+	t.Run("UpdateColumns", func(t *testing.T) {
+		repo := newRepo(t)
+		user := createUser(t, repo, "ada@example.com")
+
+		user.Name = "Ada Lovelace"
+		user.Email = "lovelace@example.com"
+		if err := repo.Update(ctx, user, "name"); err != nil {
+			t.Fatalf("Update with columns: %v", err)
+		}
+		stored := mustGet(t, repo, user.Id)
+		if stored.Name != "Ada Lovelace" || stored.Email != "ada@example.com" || stored.Version != 2 {
+			t.Errorf("stored user = %+v; want only the name and version updated", stored)
+		}
+	})
+
+	t.Run("UpdateDuplicateEmail", func(t *testing.T) {
+		repo := newRepo(t)
+		createUser(t, repo, "taken@example.com")
+		user := createUser(t, repo, "ada@example.com")
+
+		user.Email = "taken@example.com"
+		checkCode(t, "Update", repo.Update(ctx, user), appErrors.ErrUserEmailExists)
+	})
+
+	t.Run("DeleteIsSoftAndIdempotent", func(t *testing.T) {
+		repo := newRepo(t)
+		user := createUser(t, repo, "ada@example.com")
+
+		for range 2 {
+			if err := repo.Delete(ctx, int64(user.Id)); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+		}
+		if err := repo.Delete(ctx, 42); err != nil {
+			t.Errorf("Delete of a missing user: %v", err)
+		}
+		if got, err := repo.GetByID(ctx, int64(user.Id)); got != nil || err != nil {
+			t.Errorf("GetByID of a deleted user = %v, %v; want nil, nil", got, err)
+		}
+		if got, err := repo.GetByEmail(ctx, user.Email); got != nil || err != nil {
+			t.Errorf("GetByEmail of a deleted user = %v, %v; want nil, nil", got, err)
+		}
+		deleted, err := repo.GetByIDWithDeleted(ctx, int64(user.Id))
+		if err != nil || deleted == nil {
+			t.Fatalf("GetByIDWithDeleted = %v, %v; want the user", deleted, err)
+		}
+		if !deleted.DeletedAt.Valid {
+			t.Error("deleted user has no deleted_at")
+		}
+		checkRecent(t, "deleted_at", deleted.DeletedAt.Time)
+
+		checkCode(t, "Update of a deleted user", repo.Update(ctx, user), appErrors.ErrAborted)
+	})
+
+	t.Run("DeletedEmailIsReusable", func(t *testing.T) {
+		repo := newRepo(t)
+		user := createUser(t, repo, "ada@example.com")
+		if err := repo.Delete(ctx, int64(user.Id)); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if reused := createUser(t, repo, "ada@example.com"); reused.Id == user.Id {
+			t.Error("Create reused the id of a deleted user")
+		}
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		repo := newRepo(t)
+		user := createUser(t, repo, "ada@example.com")
+		if err := repo.Delete(ctx, int64(user.Id)); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+
+		for range 2 {
+			if err := repo.Restore(ctx, int64(user.Id)); err != nil {
+				t.Fatalf("Restore: %v", err)
+			}
+		}
+		if err := repo.Restore(ctx, 42); err != nil {
+			t.Errorf("Restore of a missing user: %v", err)
+		}
+		restored := mustGet(t, repo, user.Id)
+		if restored.DeletedAt.Valid || restored.Version != 2 {
+			t.Errorf("restored user = %+v; want it live at version 2", restored)
+		}
+		if got, err := repo.GetByEmail(ctx, user.Email); err != nil || got == nil {
+			t.Errorf("GetByEmail of a restored user = %v, %v; want the user", got, err)
+		}
+	})
+
+	t.Run("RestoreTakenEmail", func(t *testing.T) {
+		repo := newRepo(t)
+		user := createUser(t, repo, "ada@example.com")
+		if err := repo.Delete(ctx, int64(user.Id)); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		createUser(t, repo, "ada@example.com")
+
+		checkCode(t, "Restore", repo.Restore(ctx, int64(user.Id)), appErrors.ErrUserEmailExists)
+		if got, _ := repo.GetByID(ctx, int64(user.Id)); got != nil {
+			t.Error("failed Restore undeleted the user")
+		}
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		repo := newRepo(t)
+		live := createUser(t, repo, "live@example.com")
+		var deleted []uint32
+		for i := range 3 {
+			user := createUser(t, repo, fmt.Sprintf("deleted%d@example.com", i))
+			if err := repo.Delete(ctx, int64(user.Id)); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			deleted = append(deleted, user.Id)
+		}
+
+		if n, err := repo.Purge(ctx, time.Now().Add(-time.Hour), 10); n != 0 || err != nil {
+			t.Errorf("Purge of users deleted an hour ago = %d, %v; want 0, nil", n, err)
+		}
+		cutoff := time.Now().Add(clockSkew)
+		if n, err := repo.Purge(ctx, cutoff, 2); n != 2 || err != nil {
+			t.Fatalf("Purge with limit 2 = %d, %v; want 2, nil", n, err)
+		}
+		if got, _ := repo.GetByIDWithDeleted(ctx, int64(deleted[0])); got != nil {
+			t.Error("Purge did not delete the lowest ids first")
+		}
+		if n, err := repo.Purge(ctx, cutoff, 2); n != 1 || err != nil {
+			t.Fatalf("second Purge = %d, %v; want 1, nil", n, err)
+		}
+		for _, id := range deleted {
+			if got, _ := repo.GetByIDWithDeleted(ctx, int64(id)); got != nil {
+				t.Errorf("user %d was not purged", id)
+			}
+		}
+		mustGet(t, repo, live.Id)
+	})
+
+	t.Run("ListPages", func(t *testing.T) {
+		repo := newRepo(t)
+		var ids []uint32
+		for i := range 5 {
+			ids = append(ids, createUser(t, repo, fmt.Sprintf("user%d@example.com", i)).Id)
+		}
+		if err := repo.Delete(ctx, int64(ids[1])); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+
+		if got := listIDs(t, repo, repository.ListOptions{PageSize: 2}); fmt.Sprint(got) != fmt.Sprint([]uint32{ids[0], ids[2], ids[3], ids[4]}) {
+			t.Errorf("List = %v; want live users %v in order", got, []uint32{ids[0], ids[2], ids[3], ids[4]})
+		}
+		if got := listIDs(t, repo, repository.ListOptions{PageSize: 2, IncludeDeleted: true}); fmt.Sprint(got) != fmt.Sprint(ids) {
+			t.Errorf("List with deleted = %v; want %v", got, ids)
+		}
+
+		page, err := repo.List(ctx, repository.ListOptions{Columns: []string{"email"}})
+		if err != nil {
+			t.Fatalf("List with columns: %v", err)
+		}
+		if first := page.Items[0]; first.Id != ids[0] || first.Email != "user0@example.com" {
+			t.Errorf("List with columns returned %+v; want id %d and its email", first, ids[0])
+		}
+
+		_, err = repo.List(ctx, repository.ListOptions{PageToken: "not a token"})
+		checkCode(t, "List with an invalid page token", err, appErrors.ErrInvalidArgument)
+	})
+
+	t.Run("ConcurrentCreates", func(t *testing.T) {
+		repo := newRepo(t)
+		const n = 20
+
+		var wg sync.WaitGroup
+		users := make([]*userpb.UserRecordORM, n)
+		errs := make([]error, n)
+		for i := range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				users[i] = &userpb.UserRecordORM{Name: "User", Email: fmt.Sprintf("user%d@example.com", i)}
+				errs[i] = repo.Create(ctx, users[i])
+			}()
+		}
+		wg.Wait()
+		seen := make(map[uint32]bool)
+		for i, user := range users {
+			if errs[i] != nil {
+				t.Fatalf("Create: %v", errs[i])
+			}
+			if seen[user.Id] {
+				t.Errorf("id %d was assigned twice", user.Id)
+			}
+			seen[user.Id] = true
+		}
+
+		var created int
+		var mu sync.Mutex
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := repo.Create(ctx, &userpb.UserRecordORM{Name: "Same", Email: "same@example.com"})
+				mu.Lock()
+				defer mu.Unlock()
+				if err == nil {
+					created++
+				} else if !errors.Is(err, appErrors.ErrUserEmailExists) {
+					t.Errorf("concurrent Create of one email: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if created != 1 {
+			t.Errorf("%d concurrent Creates of one email succeeded, want 1", created)
+		}
+	})
+}
+
+// createUser creates a user with email and returns it as stored
+func createUser(t *testing.T, repo repository.UserRepository, email string) *userpb.UserRecordORM {
+	t.Helper()
+	user := &userpb.UserRecordORM{Name: "Ada", Email: email}
+	if err := repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create(%s): %v", email, err)
+	}
+	if user.Id == 0 {
+		t.Fatalf("Create(%s) assigned no id", email)
+	}
+	return user
+}
+
+// mustGet reads the live user with id
+func mustGet(t *testing.T, repo repository.UserRepository, id uint32) *userpb.UserRecordORM {
+	t.Helper()
+	user, err := repo.GetByID(context.Background(), int64(id))
+	if err != nil || user == nil {
+		t.Fatalf("GetByID(%d) = %v, %v; want the user", id, user, err)
+	}
+	return user
+}
+
+// listIDs reads every page of users and returns their IDs
+func listIDs(t *testing.T, repo repository.UserRepository, opts repository.ListOptions) []uint32 {
+	t.Helper()
+	var ids []uint32
+	for {
+		page, err := repo.List(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(page.Items) > repository.PageSize(opts.PageSize) {
+			t.Fatalf("List returned %d users, more than the page size", len(page.Items))
+		}
+		for _, user := range page.Items {
+			ids = append(ids, user.Id)
+		}
+		if page.NextPageToken == "" {
+			return ids
+		}
+		opts.PageToken = page.NextPageToken
+	}
+}
+
+// checkSame fails unless got is the stored form of want
+func checkSame(t *testing.T, got, want *userpb.UserRecordORM) {
+	t.Helper()
+	if got.Id != want.Id || got.Name != want.Name || got.Email != want.Email || got.Version != want.Version {
+		t.Errorf("got user %+v; want %+v", got, want)
+	}
+	if got.CreatedAt.Sub(want.CreatedAt).Abs() > clockSkew {
+		t.Errorf("got created_at %v; want %v", got.CreatedAt, want.CreatedAt)
+	}
+}
+
+// checkRecent fails unless the timestamp was set about now
+func checkRecent(t *testing.T, column string, at time.Time) {
+	t.Helper()
+	if time.Since(at).Abs() > clockSkew {
+		t.Errorf("%s = %v; want about now", column, at)
+	}
+}
+
+// checkCode fails unless err carries the code of want
+func checkCode(t *testing.T, operation string, err error, want appErrors.CodeErr) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Errorf("%s: got error %v; want %v", operation, err, want)
+	}
+}
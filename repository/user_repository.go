@@ -5,11 +5,9 @@ import (
 	userpb "github.com/harryosmar/protobuf-go/gen/user"
 )
 
-// UserRepository defines the interface for user data operations
+// UserRepository defines the interface for user data operations. Users are soft-deleted,
+// and GetByEmail only finds live users.
 type UserRepository interface {
-	Create(ctx context.Context, user *userpb.UserEntityORM) error
-	GetByID(ctx context.Context, id int64) (*userpb.UserEntityORM, error)
-	GetByEmail(ctx context.Context, email string) (*userpb.UserEntityORM, error)
-	Update(ctx context.Context, user *userpb.UserEntityORM) error
-	Delete(ctx context.Context, id int64) error
+	SoftDeleteRepository[userpb.UserRecordORM]
+	GetByEmail(ctx context.Context, email string) (*userpb.UserRecordORM, error)
 }
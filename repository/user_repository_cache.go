@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/harryosmar/protobuf-go/cache"
+	userpb "github.com/harryosmar/protobuf-go/gen/user"
+	"github.com/harryosmar/protobuf-go/logger"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// userCacheName labels the metrics of the user cache
+const userCacheName = "users"
+
+// CacheOptions configures a caching repository
+type CacheOptions struct {
+	TTL         time.Duration  // how long rows are cached
+	NegativeTTL time.Duration  // how long lookups that found nothing are cached, 0 disables
+	KeyPrefix   string         // namespaces the keys in a shared backend, e.g. "users:"
+	Metrics     *cache.Metrics // counts lookups, nil records nothing
+}
+
+// cachedUserRepository caches GetByID and GetByEmail of another UserRepository
+type cachedUserRepository struct {
+	UserRepository // reads and writes that are not cached go straight through
+	backend        cache.Backend
+	opts           CacheOptions
+	loads          singleflight.Group
+}
+
+// NewCachedUserRepository wraps next with a read-through cache of live users by ID and
+// by email. Concurrent misses on the same key share one load, lookups that found nothing
+// are cached for NegativeTTL, and Create, Update, Delete and Restore invalidate the
+// entries they affect. Rows are always cached whole, so GetByID ignores its columns.
+//
+// Reads may return a row changed by another instance, or by a load racing an
+// invalidation, for up to TTL; Update still detects that through the version. Backend
+// failures are logged and the lookup falls back to next.
+func NewCachedUserRepository(next UserRepository, backend cache.Backend, opts CacheOptions) UserRepository {
+	return &cachedUserRepository{
+		UserRepository: next,
+		backend:        backend,
+		opts:           opts,
+	}
+}
+
+// GetByID retrieves a live user by ID, from the cache when possible
+func (r *cachedUserRepository) GetByID(ctx context.Context, id int64, columns ...string) (*userpb.UserRecordORM, error) {
+	key := r.idKey(id)
+	if data, found := r.lookup(ctx, key); found {
+		user, err := decodeUser(data)
+		if err == nil {
+			return user, nil
+		}
+		logger.FromContext(ctx).Warn("Discarding undecodable user cache entry", zap.String("key", key), zap.Error(err))
+	}
+
+	data, err := r.load(ctx, key, func(ctx context.Context) (*userpb.UserRecordORM, error) {
+		return r.UserRepository.GetByID(ctx, id)
+	}, encodeUser)
+	if err != nil {
+		return nil, err
+	}
+	return decodeUser(data)
+}
+
+// GetByEmail retrieves a live user by email, from the cache when possible. The email
+// entry only holds the ID, and the user is then read by ID; an entry left stale by an
+// email change is detected and reloaded.
+func (r *cachedUserRepository) GetByEmail(ctx context.Context, email string) (*userpb.UserRecordORM, error) {
+	key := r.emailKey(email)
+	if data, found := r.lookup(ctx, key); found {
+		if len(data) == 0 {
+			return nil, nil
+		}
+		if id, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+			user, err := r.GetByID(ctx, id)
+			if err != nil || user != nil && user.Email == email {
+				return user, err
+			}
+		}
+	}
+
+	data, err := r.load(ctx, key, func(ctx context.Context) (*userpb.UserRecordORM, error) {
+		user, err := r.UserRepository.GetByEmail(ctx, email)
+		if err != nil || user == nil {
+			return user, err
+		}
+		// Cache the ID under the email key and the row under the ID key
+		r.set(ctx, r.idKey(int64(user.Id)), encodeUser(user), r.opts.TTL)
+		return user, nil
+	}, r.emailValue)
+	if err != nil {
+		return nil, err
+	}
+	return decodeUser(data)
+}
+
+// Create inserts user and invalidates the not-found entries of its ID and email
+func (r *cachedUserRepository) Create(ctx context.Context, user *userpb.UserRecordORM) error {
+	err := r.UserRepository.Create(ctx, user)
+	if err == nil {
+		r.invalidate(ctx, r.idKey(int64(user.Id)), r.emailKey(user.Email))
+	}
+	return err
+}
+
+// Update saves user and invalidates its entries. They are invalidated even when the
+// update fails, since a version conflict means the cached row is stale.
+func (r *cachedUserRepository) Update(ctx context.Context, user *userpb.UserRecordORM, columns ...string) error {
+	err := r.UserRepository.Update(ctx, user, columns...)
+	r.invalidate(ctx, r.idKey(int64(user.Id)), r.emailKey(user.Email))
+	return err
+}
+
+// Delete soft-deletes a user and invalidates its ID entry; its email entry then points
+// to a user that is not found and is reloaded
+func (r *cachedUserRepository) Delete(ctx context.Context, id int64) error {
+	err := r.UserRepository.Delete(ctx, id)
+	r.invalidate(ctx, r.idKey(id))
+	return err
+}
+
+// Restore undeletes a user and invalidates its entries, including a not-found entry of
+// its email cached while it was deleted
+func (r *cachedUserRepository) Restore(ctx context.Context, id int64) error {
+	if err := r.UserRepository.Restore(ctx, id); err != nil {
+		return err
+	}
+	keys := []string{r.idKey(id)}
+	if user, err := r.UserRepository.GetByID(ctx, id); err == nil && user != nil {
+		keys = append(keys, r.emailKey(user.Email))
+	}
+	r.invalidate(ctx, keys...)
+	return nil
+}
+
+// lookup returns the cached value of key, recording the result. A found empty value is
+// a cached not-found.
+func (r *cachedUserRepository) lookup(ctx context.Context, key string) ([]byte, bool) {
+	data, found, err := r.backend.Get(ctx, key)
+	switch {
+	case err != nil:
+		logger.FromContext(ctx).Warn("User cache lookup failed", zap.String("key", key), zap.Error(err))
+		r.opts.Metrics.Record(userCacheName, cache.Error)
+		return nil, false
+	case !found:
+		r.opts.Metrics.Record(userCacheName, cache.Miss)
+	case len(data) == 0:
+		r.opts.Metrics.Record(userCacheName, cache.NegativeHit)
+	default:
+		r.opts.Metrics.Record(userCacheName, cache.Hit)
+	}
+	return data, found
+}
+
+// load reads a user with fetch and caches it under key as encoded by encode. Concurrent
+// loads of the same key share one fetch, which is not canceled when the caller that
+// started it goes away. It returns the encoded row, empty when the user was not found.
+func (r *cachedUserRepository) load(ctx context.Context, key string, fetch func(ctx context.Context) (*userpb.UserRecordORM, error), encode func(*userpb.UserRecordORM) []byte) ([]byte, error) {
+	data, err, _ := r.loads.Do(key, func() (any, error) {
+		ctx := context.WithoutCancel(ctx)
+		user, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			r.set(ctx, key, nil, r.opts.NegativeTTL)
+			return []byte(nil), nil
+		}
+
+		r.set(ctx, key, encode(user), r.opts.TTL)
+		return encodeUser(user), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data.([]byte), nil
+}
+
+// set caches value under key for ttl; a ttl of 0 caches nothing
+func (r *cachedUserRepository) set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	if err := r.backend.Set(ctx, key, value, ttl); err != nil {
+		logger.FromContext(ctx).Warn("User cache store failed", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// invalidate removes keys from the cache and lets the next lookup of each start a new
+// load. It runs even when the caller went away after its write was saved.
+func (r *cachedUserRepository) invalidate(ctx context.Context, keys ...string) {
+	ctx = context.WithoutCancel(ctx)
+	for _, key := range keys {
+		r.loads.Forget(key)
+	}
+	if err := r.backend.Delete(ctx, keys...); err != nil {
+		logger.FromContext(ctx).Error("User cache invalidation failed, entries stay stale until they expire",
+			zap.Strings("keys", keys), zap.Error(err))
+	}
+}
+
+// idKey is the cache key of the user with id
+func (r *cachedUserRepository) idKey(id int64) string {
+	return r.opts.KeyPrefix + "id:" + strconv.FormatInt(id, 10)
+}
+
+// emailKey is the cache key of the user with email
+func (r *cachedUserRepository) emailKey(email string) string {
+	return r.opts.KeyPrefix + "email:" + email
+}
+
+// emailValue is the value cached under an email key: the user's ID
+func (r *cachedUserRepository) emailValue(user *userpb.UserRecordORM) []byte {
+	return strconv.AppendUint(nil, uint64(user.Id), 10)
+}
+
+// encodeUser encodes a row for the cache
+func encodeUser(user *userpb.UserRecordORM) []byte {
+	data, _ := json.Marshal(user) // a row of plain fields always encodes
+	return data
+}
+
+// decodeUser decodes a cached row, or returns nil for an empty (not-found) value. Every
+// caller gets its own copy, so rows can be modified freely.
+func decodeUser(data []byte) (*userpb.UserRecordORM, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var user userpb.UserRecordORM
+	if err := json.Unmarshal(data, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
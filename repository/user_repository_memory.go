@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	appErrors "github.com/harryosmar/protobuf-go/error"
+	userpb "github.com/harryosmar/protobuf-go/gen/user"
+	"gorm.io/gorm"
+)
+
+// errDuplicateEmail is the cause of ErrUserEmailExists in the in-memory repository,
+// standing in for the MySQL duplicate entry error
+var errDuplicateEmail = errors.New("duplicate entry for email_active_idx")
+
+// userMemoryColumns copies each users column from one row to another
+var userMemoryColumns = map[string]func(dst, src *userpb.UserRecordORM){
+	"id":         func(dst, src *userpb.UserRecordORM) { dst.Id = src.Id },
+	"name":       func(dst, src *userpb.UserRecordORM) { dst.Name = src.Name },
+	"email":      func(dst, src *userpb.UserRecordORM) { dst.Email = src.Email },
+	"created_at": func(dst, src *userpb.UserRecordORM) { dst.CreatedAt = src.CreatedAt },
+	"updated_at": func(dst, src *userpb.UserRecordORM) { dst.UpdatedAt = src.UpdatedAt },
+	"deleted_at": func(dst, src *userpb.UserRecordORM) { dst.DeletedAt = src.DeletedAt },
+	"version":    func(dst, src *userpb.UserRecordORM) { dst.Version = src.Version },
+}
+
+// userRepositoryMemory implements UserRepository in process memory
+type userRepositoryMemory struct {
+	mu     sync.RWMutex
+	rows   map[int64]userpb.UserRecordORM
+	lastID int64
+}
+
+// NewUserRepositoryMemory creates an empty in-memory user repository for tests and local
+// development. It is safe for concurrent use and behaves like the MySQL repository: IDs
+// auto-increment, deletes are soft, emails are unique among live users (compared
+// case-insensitively, like the MySQL collation), updates are versioned and not found
+// returns nil. Data is lost when the process exits.
+func NewUserRepositoryMemory() UserRepository {
+	return &userRepositoryMemory{rows: make(map[int64]userpb.UserRecordORM)}
+}
+
+// Create inserts user, assigning the next ID unless it has one, and stamps it like GORM
+func (r *userRepositoryMemory) Create(ctx context.Context, user *userpb.UserRecordORM) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := int64(user.Id)
+	if _, exists := r.rows[id]; exists && id != 0 || r.emailTaken(user.Email, 0) {
+		return appErrors.ErrUserEmailExists.Wrap(errDuplicateEmail)
+	}
+	if id == 0 {
+		id = r.lastID + 1
+	}
+	r.lastID = max(r.lastID, id)
+
+	now := time.Now()
+	user.Id = uint32(id)
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+	user.Version = 1
+	r.rows[id] = *user
+	return nil
+}
+
+// GetByID retrieves a live user by ID
+func (r *userRepositoryMemory) GetByID(ctx context.Context, id int64, columns ...string) (*userpb.UserRecordORM, error) {
+	return r.get(id, false, columns)
+}
+
+// GetByIDWithDeleted retrieves a user by ID even if it is deleted
+func (r *userRepositoryMemory) GetByIDWithDeleted(ctx context.Context, id int64, columns ...string) (*userpb.UserRecordORM, error) {
+	return r.get(id, true, columns)
+}
+
+// GetByEmail retrieves a live user by email
+func (r *userRepositoryMemory) GetByEmail(ctx context.Context, email string) (*userpb.UserRecordORM, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, row := range r.rows {
+		if !row.DeletedAt.Valid && strings.EqualFold(row.Email, email) {
+			return &row, nil
+		}
+	}
+	return nil, nil
+}
+
+// Update saves user, or only columns of it, while the stored version still equals
+// user's, then increments it; otherwise it fails with ErrAborted
+func (r *userRepositoryMemory) Update(ctx context.Context, user *userpb.UserRecordORM, columns ...string) error {
+	if err := checkUserMemoryColumns(columns); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := int64(user.Id)
+	stored, exists := r.rows[id]
+	if !exists || stored.DeletedAt.Valid || stored.Version != user.Version {
+		return appErrors.ErrAborted.WithTemplate("version_conflict", appErrors.Params{"version": user.Version})
+	}
+	if (len(columns) == 0 || slices.Contains(columns, "email")) && r.emailTaken(user.Email, id) {
+		return appErrors.ErrUserEmailExists.Wrap(errDuplicateEmail)
+	}
+
+	user.Version++
+	user.UpdatedAt = time.Now()
+	if len(columns) == 0 {
+		stored = *user
+	} else {
+		for _, column := range append(columns, "version", "updated_at") {
+			userMemoryColumns[column](&stored, user)
+		}
+	}
+	r.rows[id] = stored
+	return nil
+}
+
+// Delete marks a live user as deleted; deleting a deleted or missing user is a no-op
+func (r *userRepositoryMemory) Delete(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if row, exists := r.rows[id]; exists && !row.DeletedAt.Valid {
+		row.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		r.rows[id] = row
+	}
+	return nil
+}
+
+// Restore undeletes a deleted user, incrementing its version. Restoring a live or missing
+// user is a no-op; a user whose email was taken meanwhile fails with ErrUserEmailExists.
+func (r *userRepositoryMemory) Restore(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row, exists := r.rows[id]
+	if !exists || !row.DeletedAt.Valid {
+		return nil
+	}
+	if r.emailTaken(row.Email, id) {
+		return appErrors.ErrUserEmailExists.Wrap(errDuplicateEmail)
+	}
+	row.DeletedAt = gorm.DeletedAt{}
+	row.Version++
+	row.UpdatedAt = time.Now()
+	r.rows[id] = row
+	return nil
+}
+
+// Purge permanently deletes up to limit users deleted before deletedBefore, lowest IDs
+// first, and returns how many were deleted
+func (r *userRepositoryMemory) Purge(ctx context.Context, deletedBefore time.Time, limit int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ids []int64
+	for id, row := range r.rows {
+		if row.DeletedAt.Valid && row.DeletedAt.Time.Before(deletedBefore) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	for _, id := range ids {
+		delete(r.rows, id)
+	}
+	return int64(len(ids)), nil
+}
+
+// List returns one page of users ordered by ID
+func (r *userRepositoryMemory) List(ctx context.Context, opts ListOptions) (*Page[userpb.UserRecordORM], error) {
+	if err := checkUserMemoryColumns(opts.Columns); err != nil {
+		return nil, err
+	}
+	var after int64
+	if opts.PageToken != "" {
+		var err error
+		if after, err = DecodePageToken(opts.PageToken); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []int64
+	for id, row := range r.rows {
+		if id > after && (opts.IncludeDeleted || !row.DeletedAt.Valid) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	size := PageSize(opts.PageSize)
+	page := &Page[userpb.UserRecordORM]{}
+	if len(ids) > size {
+		ids = ids[:size]
+		page.NextPageToken = EncodePageToken(ids[size-1])
+	}
+	for _, id := range ids {
+		page.Items = append(page.Items, projectUser(r.rows[id], opts.Columns))
+	}
+	return page, nil
+}
+
+// get returns a copy of the user with id, reading only columns when given
+func (r *userRepositoryMemory) get(id int64, withDeleted bool, columns []string) (*userpb.UserRecordORM, error) {
+	if err := checkUserMemoryColumns(columns); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	row, exists := r.rows[id]
+	if !exists || row.DeletedAt.Valid && !withDeleted {
+		return nil, nil
+	}
+	return projectUser(row, columns), nil
+}
+
+// emailTaken reports whether a live user other than exceptID has email; the caller holds mu
+func (r *userRepositoryMemory) emailTaken(email string, exceptID int64) bool {
+	for id, row := range r.rows {
+		if id != exceptID && !row.DeletedAt.Valid && strings.EqualFold(row.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// projectUser returns a copy of row with only columns and the ID set, or all of it when
+// no columns are given
+func projectUser(row userpb.UserRecordORM, columns []string) *userpb.UserRecordORM {
+	if len(columns) == 0 {
+		return &row
+	}
+	projected := &userpb.UserRecordORM{Id: row.Id}
+	for _, column := range columns {
+		userMemoryColumns[column](projected, &row)
+	}
+	return projected
+}
+
+// checkUserMemoryColumns fails with ErrInvalidArgument when a column does not exist,
+// like Repository does
+func checkUserMemoryColumns(columns []string) error {
+	for _, column := range columns {
+		if _, ok := userMemoryColumns[column]; !ok {
+			return appErrors.ErrInvalidArgument.WithTemplate("validation", appErrors.Params{
+				"reason": fmt.Sprintf("users has no column %q", column),
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/harryosmar/protobuf-go/cache"
+	"github.com/harryosmar/protobuf-go/repository"
+	"github.com/harryosmar/protobuf-go/repository/repositorytest"
+)
+
+func TestUserRepositoryMemory(t *testing.T) {
+	repositorytest.TestUserRepository(t, func(t *testing.T) repository.UserRepository {
+		return repository.NewUserRepositoryMemory()
+	})
+}
+
+func TestCachedUserRepository(t *testing.T) {
+	repositorytest.TestUserRepository(t, func(t *testing.T) repository.UserRepository {
+		return repository.NewCachedUserRepository(repository.NewUserRepositoryMemory(), cache.NewLRU(100), repository.CacheOptions{
+			TTL:         time.Minute,
+			NegativeTTL: time.Minute,
+			KeyPrefix:   "users:",
+		})
+	})
+}
@@ -2,74 +2,153 @@ package repository
 
 import (
 	"context"
-	"errors"
-	appErrors "github.com/harryosmar/protobuf-go/error"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
 
-	"github.com/go-sql-driver/mysql"
+	appErrors "github.com/harryosmar/protobuf-go/error"
 	userpb "github.com/harryosmar/protobuf-go/gen/user"
 	"gorm.io/gorm"
 )
 
 // userRepositoryMySQL implements UserRepository interface
 type userRepositoryMySQL struct {
-	db *gorm.DB
+	*Repository[userpb.UserRecordORM, userpb.UserRecord]
 }
 
 // NewUserRepositoryMySQL creates a new user repository instance
 func NewUserRepositoryMySQL(db *gorm.DB) UserRepository {
 	return &userRepositoryMySQL{
-		db: db,
+		Repository: NewRepository[userpb.UserRecordORM, userpb.UserRecord](db, ErrorCodes{
+			Exists:         appErrors.ErrUserEmailExists,
+			CreationFailed: appErrors.ErrUserCreationFailed,
+			UpdateFailed:   appErrors.ErrUserUpdateFailed,
+			DeletionFailed: appErrors.ErrUserDeletionFailed,
+		}),
 	}
 }
 
-// Create creates a new user in the database
-func (r *userRepositoryMySQL) Create(ctx context.Context, user *userpb.UserEntityORM) error {
-	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
-		// Check for MySQL duplicate entry error (Error 1062)
-		var mysqlErr *mysql.MySQLError
-		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
-			return appErrors.ErrUserEmailExists
-		}
+// MigrateUsers migrates the users table. Email uniqueness is enforced by email_active_idx
+// over email and the generated email_active column, which is 1 for live users and NULL for
+// deleted ones. A unique index admits any number of NULLs, so deleted users keep their
+// email without blocking it for new users, while restoring a user whose email was taken
+// meanwhile fails with ErrUserEmailExists. It replaces the email-only email_idx of older
+// schemas, creating the new index first so uniqueness is enforced throughout.
+//
+// Older schemas also stored created_at and updated_at as strings; they are normalized by
+// migrateUserTimestamps before AutoMigrate converts the columns to DATETIME.
+func MigrateUsers(db *gorm.DB) error {
+	model := &userpb.UserRecordORM{}
+	if err := migrateUserTimestamps(db, model); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(model); err != nil {
 		return err
 	}
-	return nil
-}
 
-// GetByID retrieves a user by ID
-func (r *userRepositoryMySQL) GetByID(ctx context.Context, id int64) (*userpb.UserEntityORM, error) {
-	var user userpb.UserEntityORM
-	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, nil // Not found is not an error at repository level
+	migrator := db.Migrator()
+	if !migrator.HasColumn(model, "email_active") {
+		err := db.Exec("ALTER TABLE users ADD COLUMN email_active TINYINT AS (IF(deleted_at IS NULL, 1, NULL)) VIRTUAL").Error
+		if err != nil {
+			return fmt.Errorf("add email_active column: %w", err)
 		}
-		return nil, err
 	}
-	return &user, nil
+	if !migrator.HasIndex(model, "email_active_idx") {
+		if err := db.Exec("CREATE UNIQUE INDEX email_active_idx ON users (email, email_active)").Error; err != nil {
+			return fmt.Errorf("create email_active_idx: %w", err)
+		}
+	}
+	if migrator.HasIndex(model, "email_idx") {
+		if err := migrator.DropIndex(model, "email_idx"); err != nil {
+			return fmt.Errorf("drop email_idx: %w", err)
+		}
+	}
+	return nil
 }
 
-// GetByEmail retrieves a user by email
-func (r *userRepositoryMySQL) GetByEmail(ctx context.Context, email string) (*userpb.UserEntityORM, error) {
-	var user userpb.UserEntityORM
-	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, nil // Not found is not an error at repository level
+// Layouts of the string created_at and updated_at values of older schemas: RFC 3339 with
+// any offset and precision, or the UTC form written by userTimestampLayout, which an
+// interrupted migration may have left behind
+var legacyUserTimestampLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999"}
+
+// userTimestampLayout is the UTC form MySQL converts to DATETIME(3)
+const userTimestampLayout = "2006-01-02 15:04:05.000"
+
+// migrateUserTimestamps rewrites string created_at and updated_at values as UTC in a form
+// MySQL converts to DATETIME: values are parsed in Go so that offsets such as +07:00 keep
+// their instant, and empty values, which were never set, become the current time. Every
+// value is rewritten in one transaction, and an unparseable one aborts the migration
+// before anything changes. Columns that are already DATETIME are left alone, so it is a
+// no-op on current schemas.
+func migrateUserTimestamps(db *gorm.DB, model *userpb.UserRecordORM) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(model) {
+		return nil
+	}
+	columnTypes, err := migrator.ColumnTypes(model)
+	if err != nil {
+		return fmt.Errorf("read users column types: %w", err)
+	}
+
+	var columns []string
+	for _, columnType := range columnTypes {
+		name := columnType.Name()
+		if name != "created_at" && name != "updated_at" {
+			continue
 		}
-		return nil, err
+		switch strings.ToLower(columnType.DatabaseTypeName()) {
+		case "datetime", "timestamp":
+			continue
+		}
+		columns = append(columns, name)
+	}
+	if len(columns) == 0 {
+		return nil
 	}
-	return &user, nil
-}
 
-// Update updates an existing user
-func (r *userRepositoryMySQL) Update(ctx context.Context, user *userpb.UserEntityORM) error {
-	return r.db.WithContext(ctx).Save(user).Error
+	now := time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, name := range columns {
+			var rows []struct {
+				ID    int64
+				Value sql.NullString
+			}
+			if err := tx.Table("users").Select("id, " + name + " AS value").Scan(&rows).Error; err != nil {
+				return fmt.Errorf("read %s: %w", name, err)
+			}
+			for _, row := range rows {
+				normalized, err := normalizeUserTimestamp(row.Value.String, now)
+				if err != nil {
+					return fmt.Errorf("normalize %s of user %d: %w", name, row.ID, err)
+				}
+				if row.Value.Valid && normalized == row.Value.String {
+					continue
+				}
+				if err := tx.Exec("UPDATE users SET "+name+" = ? WHERE id = ?", normalized, row.ID).Error; err != nil {
+					return fmt.Errorf("normalize %s of user %d: %w", name, row.ID, err)
+				}
+			}
+		}
+		return nil
+	})
 }
 
-// Delete deletes a user by ID
-func (r *userRepositoryMySQL) Delete(ctx context.Context, id int64) error {
-	result := r.db.WithContext(ctx).Delete(&userpb.UserEntityORM{}, id)
-	if result.Error != nil {
-		return result.Error
+// normalizeUserTimestamp returns a legacy timestamp as UTC in userTimestampLayout, now
+// when it is empty
+func normalizeUserTimestamp(value string, now time.Time) (string, error) {
+	if value == "" {
+		return now.UTC().Format(userTimestampLayout), nil
 	}
-	// Return success even if no rows affected - idempotent delete
-	return nil
+	for _, layout := range legacyUserTimestampLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.UTC().Format(userTimestampLayout), nil
+		}
+	}
+	return "", fmt.Errorf("unrecognized timestamp %q", value)
+}
+
+// GetByEmail retrieves a user by email
+func (r *userRepositoryMySQL) GetByEmail(ctx context.Context, email string) (*userpb.UserRecordORM, error) {
+	return r.GetBy(ctx, "email", email)
 }
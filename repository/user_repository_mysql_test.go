@@ -0,0 +1,116 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/harryosmar/protobuf-go/repository"
+	"github.com/harryosmar/protobuf-go/repository/repositorytest"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDatabaseEnv names the DSN of a scratch MySQL database whose users table the tests
+// drop and recreate, e.g. root:secret@tcp(127.0.0.1:3306)/protobuf_go_test
+const testDatabaseEnv = "TEST_DATABASE_URL"
+
+// openTestDatabase connects to the database named by TEST_DATABASE_URL with an empty
+// users table, skipping the test when the variable is unset
+func openTestDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv(testDatabaseEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseEnv)
+	}
+	mysqlConfig, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("%s: %v", testDatabaseEnv, err)
+	}
+	mysqlConfig.ParseTime = true
+	mysqlConfig.Loc = time.UTC
+
+	db, err := gorm.Open(mysql.New(mysql.Config{DSNConfig: mysqlConfig}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open %s: %v", testDatabaseEnv, err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.Migrator().DropTable("users"); err != nil {
+		t.Fatalf("drop users: %v", err)
+	}
+	return db
+}
+
+func TestUserRepositoryMySQL(t *testing.T) {
+	db := openTestDatabase(t)
+	if err := repository.MigrateUsers(db); err != nil {
+		t.Fatalf("migrate users: %v", err)
+	}
+
+	repositorytest.TestUserRepository(t, func(t *testing.T) repository.UserRepository {
+		if err := db.Exec("TRUNCATE TABLE users").Error; err != nil {
+			t.Fatalf("truncate users: %v", err)
+		}
+		return repository.NewUserRepositoryMySQL(db)
+	})
+}
+
+func TestMigrateUsersLegacyTimestamps(t *testing.T) {
+	db := openTestDatabase(t)
+	err := db.Exec(`CREATE TABLE users (
+		id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(100) NOT NULL,
+		email VARCHAR(255),
+		created_at VARCHAR(64),
+		updated_at VARCHAR(64),
+		deleted_at DATETIME(3) NULL,
+		version BIGINT NOT NULL DEFAULT 1
+	)`).Error
+	if err != nil {
+		t.Fatalf("create legacy users: %v", err)
+	}
+	legacy := []struct {
+		email     string
+		createdAt string
+		updatedAt string
+		want      time.Time
+	}{
+		{"utc@example.com", "2024-01-02T03:04:05Z", "2024-01-02T03:04:05.123Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"east@example.com", "2024-01-02T10:04:05+07:00", "2024-01-02T10:04:05.9876543+07:00", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"west@example.com", "2024-01-01T22:04:05-05:00", "", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+	for _, user := range legacy {
+		err := db.Exec("INSERT INTO users (name, email, created_at, updated_at) VALUES (?, ?, ?, ?)",
+			"Legacy", user.email, user.createdAt, user.updatedAt).Error
+		if err != nil {
+			t.Fatalf("insert %s: %v", user.email, err)
+		}
+	}
+
+	if err := repository.MigrateUsers(db); err != nil {
+		t.Fatalf("migrate users: %v", err)
+	}
+
+	repo := repository.NewUserRepositoryMySQL(db)
+	for _, user := range legacy {
+		got, err := repo.GetByEmail(context.Background(), user.email)
+		if err != nil || got == nil {
+			t.Fatalf("get %s = %v, %v", user.email, got, err)
+		}
+		if !got.CreatedAt.Equal(user.want) {
+			t.Errorf("%s created_at = %v, want %v", user.email, got.CreatedAt, user.want)
+		}
+		if got.UpdatedAt.IsZero() {
+			t.Errorf("%s updated_at is zero", user.email)
+		}
+	}
+}
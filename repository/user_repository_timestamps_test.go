@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeUserTimestamp(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.FixedZone("WIB", 7*60*60))
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: "2024-05-01 03:00:00.000"},
+		{value: "2024-01-02T03:04:05Z", want: "2024-01-02 03:04:05.000"},
+		{value: "2024-01-02T03:04:05.123Z", want: "2024-01-02 03:04:05.123"},
+		{value: "2024-01-02T10:04:05+07:00", want: "2024-01-02 03:04:05.000"},
+		{value: "2024-01-02T01:04:05-02:00", want: "2024-01-02 03:04:05.000"},
+		{value: "2024-01-02T00:30:00+07:00", want: "2024-01-01 17:30:00.000"},
+		{value: "2024-01-02T03:04:05.123456789Z", want: "2024-01-02 03:04:05.123"},
+		{value: "2024-01-02T10:04:05.9876543+07:00", want: "2024-01-02 03:04:05.987"},
+		{value: "2024-01-02 03:04:05.123", want: "2024-01-02 03:04:05.123"},
+	}
+	for _, tt := range tests {
+		got, err := normalizeUserTimestamp(tt.value, now)
+		if err != nil {
+			t.Errorf("normalizeUserTimestamp(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeUserTimestamp(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+
+	if _, err := normalizeUserTimestamp("yesterday", now); err == nil {
+		t.Error("normalizeUserTimestamp(\"yesterday\") succeeded, want an error")
+	}
+}
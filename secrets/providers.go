@@ -0,0 +1,82 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// FileProvider reads file:///path/to/secret, trimming the trailing newline
+type FileProvider struct{}
+
+// Resolve implements Provider
+func (FileProvider) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	if ref.Path == "" {
+		return "", errors.New("file reference needs an absolute path, e.g. file:///run/secrets/db")
+	}
+	data, err := os.ReadFile(ref.Path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// EnvProvider reads env://NAME from the environment variable NAME
+type EnvProvider struct{}
+
+// Resolve implements Provider
+func (EnvProvider) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	name := ref.Host
+	if name == "" {
+		return "", errors.New("env reference needs a variable name, e.g. env://DB_PASSWORD")
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// LocalVaultProvider stands in for Vault during development. It resolves
+// vault://<path>#<key> from a JSON file mapping each path to its key/value pairs:
+//
+//	{"secret/db": {"url": "user:pass@tcp(db:3306)/app", "password": "pass"}}
+//
+// The key defaults to "value". The file is read on every lookup so edits act as rotations.
+type LocalVaultProvider struct {
+	file string
+}
+
+// NewLocalVaultProvider creates a vault stand-in backed by file
+func NewLocalVaultProvider(file string) *LocalVaultProvider {
+	return &LocalVaultProvider{file: file}
+}
+
+// Resolve implements Provider
+func (p *LocalVaultProvider) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	data, err := os.ReadFile(p.file)
+	if err != nil {
+		return "", err
+	}
+
+	var store map[string]map[string]string
+	if err := json.Unmarshal(data, &store); err != nil {
+		return "", fmt.Errorf("invalid vault file %s: %w", p.file, err)
+	}
+
+	path := strings.Trim(ref.Host+ref.Path, "/")
+	key := ref.Fragment
+	if key == "" {
+		key = "value"
+	}
+
+	value, ok := store[path][key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found at path %q", key, path)
+	}
+	return value, nil
+}
@@ -0,0 +1,86 @@
+package secrets
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Secret holds the resolved value of a reference and refreshes it to detect rotation
+type Secret struct {
+	ref      string
+	resolver *Resolver
+	value    string
+	mutex    sync.RWMutex
+}
+
+// NewSecret resolves ref immediately. A plain value that is not a reference never rotates.
+func (r *Resolver) NewSecret(ctx context.Context, ref string) (*Secret, error) {
+	value, err := r.Resolve(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &Secret{ref: ref, resolver: r, value: value}, nil
+}
+
+// Value returns the latest resolved value
+func (s *Secret) Value() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.value
+}
+
+// Reference returns the reference the secret was created from; it is safe to log
+func (s *Secret) Reference() string {
+	if !IsReference(s.ref) {
+		return "(inline)"
+	}
+	return s.ref
+}
+
+// Refresh resolves the reference again and reports whether the value changed
+func (s *Secret) Refresh(ctx context.Context) (bool, error) {
+	if !IsReference(s.ref) {
+		return false, nil
+	}
+
+	value, err := s.resolver.Resolve(ctx, s.ref)
+	if err != nil {
+		return false, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if value == s.value {
+		return false, nil
+	}
+	s.value = value
+	return true, nil
+}
+
+// Watch refreshes the secret every interval until ctx is done, calling onRotate after
+// each change and onError when resolution fails. Plain values are not watched.
+func (s *Secret) Watch(ctx context.Context, interval time.Duration, onRotate func(), onError func(error)) {
+	if !IsReference(s.ref) || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rotated, err := s.Refresh(ctx)
+			if err != nil {
+				onError(err)
+				continue
+			}
+			if rotated {
+				onRotate()
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Supported reference schemes
+const (
+	SchemeFile  = "file"
+	SchemeEnv   = "env"
+	SchemeVault = "vault"
+)
+
+// Provider resolves a secret reference such as file:///run/secrets/db to its value
+type Provider interface {
+	Resolve(ctx context.Context, ref *url.URL) (string, error)
+}
+
+// ProviderFunc adapts a function to the Provider interface
+type ProviderFunc func(ctx context.Context, ref *url.URL) (string, error)
+
+// Resolve implements Provider
+func (f ProviderFunc) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	return f(ctx, ref)
+}
+
+// IsReference reports whether value uses one of the supported secret reference schemes.
+// Plain values such as a MySQL DSN are used as they are.
+func IsReference(value string) bool {
+	scheme, _, ok := strings.Cut(value, "://")
+	if !ok {
+		return false
+	}
+	switch scheme {
+	case SchemeFile, SchemeEnv, SchemeVault:
+		return true
+	}
+	return false
+}
+
+// Resolver resolves secret references using the provider registered for their scheme
+type Resolver struct {
+	providers map[string]Provider
+	mutex     sync.RWMutex
+}
+
+// NewResolver creates a resolver with the file and env providers and, when vaultFile is set,
+// the local file-backed vault stand-in
+func NewResolver(vaultFile string) *Resolver {
+	resolver := &Resolver{providers: make(map[string]Provider)}
+	resolver.Register(SchemeFile, FileProvider{})
+	resolver.Register(SchemeEnv, EnvProvider{})
+	if vaultFile != "" {
+		resolver.Register(SchemeVault, NewLocalVaultProvider(vaultFile))
+	}
+	return resolver
+}
+
+// Register sets the provider for scheme, replacing any existing one (e.g. a real Vault client)
+func (r *Resolver) Register(scheme string, provider Provider) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.providers[scheme] = provider
+}
+
+// Resolve returns the secret referenced by value, or value itself when it is not a reference.
+// Errors name the reference but never include resolved values.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+
+	ref, err := url.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid secret reference: %w", err)
+	}
+
+	r.mutex.RLock()
+	provider, ok := r.providers[ref.Scheme]
+	r.mutex.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("no secret provider configured for %s://", ref.Scheme)
+	}
+
+	secret, err := provider.Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret %s: %w", value, err)
+	}
+	return secret, nil
+}
@@ -2,10 +2,16 @@ package service
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"strings"
 
 	error2 "github.com/harryosmar/protobuf-go/error"
+	"github.com/harryosmar/protobuf-go/fieldmask"
 	userpb "github.com/harryosmar/protobuf-go/gen/user"
 	"github.com/harryosmar/protobuf-go/logger"
+	"github.com/harryosmar/protobuf-go/middleware"
+	"github.com/harryosmar/protobuf-go/repository"
 	"github.com/harryosmar/protobuf-go/usecase"
 	"go.uber.org/zap"
 )
@@ -27,22 +33,22 @@ func NewUserServiceServer(userUsecase usecase.UserUsecase) *UserServiceServer {
 func (s *UserServiceServer) CreateUser(ctx context.Context, req *userpb.CreateUserRequest) (*userpb.CreateUserResponse, error) {
 	// Get logger with request ID from context
 	log := logger.FromContext(ctx)
-	log.Info("UserService.CreateUser called", zap.String("name", req.User.Name), zap.String("email", req.User.Email))
+	log.Info("UserService.CreateUser called", logger.Proto("user", req.GetUser()))
 
 	// Validation will be handled by protoc-gen-validate generated code
 	if err := req.Validate(); err != nil {
-		return nil, error2.ErrInvalidArgument.WithMessage("validation failed: %v", err)
+		return nil, error2.ErrInvalidArgument.WithTemplate("validation", error2.Params{"reason": err})
 	}
 
 	// Call usecase to handle business logic
 	createdUser, err := s.userUsecase.CreateUser(ctx, req.User)
 	if err != nil {
-		log.Error("Failed to create user", zap.String("email", req.User.Email), zap.Error(err))
+		log.Error("Failed to create user", logger.Proto("user", req.GetUser()), zap.Error(err))
 		// Error conversion handled automatically by ErrorConversionInterceptor
 		return nil, err
 	}
 
-	log.Info("UserService.CreateUser created user", zap.String("user_name", createdUser.Name))
+	log.Info("UserService.CreateUser created user", zap.Uint32("user_id", createdUser.Id))
 	return &userpb.CreateUserResponse{
 		User: createdUser,
 	}, nil
@@ -57,19 +63,154 @@ func (s *UserServiceServer) GetUser(ctx context.Context, req *userpb.GetUserRequ
 	// Validation will be handled by protoc-gen-validate generated code
 	// Proto validation rule: [(validate.rules).int64 = {gt: 0}]
 	if err := req.Validate(); err != nil {
-		return nil, error2.ErrInvalidArgument.WithMessage("validation failed: %v", err)
+		return nil, error2.ErrInvalidArgument.WithTemplate("validation", error2.Params{"reason": err})
+	}
+
+	if err := fieldmask.Validate(req.ReadMask, &userpb.UserEntity{}); err != nil {
+		return nil, error2.ErrInvalidArgument.WithTemplate("validation", error2.Params{"reason": err})
+	}
+	if req.IncludeDeleted && !middleware.IsAdmin(ctx) {
+		return nil, error2.ErrPermissionDenied.WithTemplate("admin_only", error2.Params{"option": "include_deleted"})
 	}
 
 	// Call usecase to handle business logic
-	user, err := s.userUsecase.GetUserByID(ctx, req.Id)
+	user, err := s.userUsecase.GetUserByID(ctx, req.Id, req.IncludeDeleted, fieldmask.Paths(req.ReadMask))
 	if err != nil {
 		log.Error("Failed to get user", zap.Int64("user_id", req.Id), zap.Error(err))
 		// Error conversion handled automatically by ErrorConversionInterceptor
 		return nil, err
 	}
 
-	log.Info("UserService.GetUser found user", zap.String("user_name", user.Name))
+	log.Info("UserService.GetUser found user", zap.Int64("user_id", req.Id))
 	return &userpb.GetUserResponse{
 		User: user,
 	}, nil
 }
+
+// UpdateUser implements the UpdateUser RPC method. The If-Match metadata (or HTTP
+// header), when present, takes precedence over payload.etag.
+func (s *UserServiceServer) UpdateUser(ctx context.Context, req *userpb.UpdateUserRequest) (*userpb.UpdateUserResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("UserService.UpdateUser called", logger.Proto("user", req.GetPayload()), zap.Strings("update_mask", req.GetUpdateMask().GetPaths()))
+
+	if err := req.Validate(); err != nil {
+		return nil, error2.ErrInvalidArgument.WithTemplate("validation", error2.Params{"reason": err})
+	}
+	if err := fieldmask.Validate(req.UpdateMask, &userpb.UserEntity{}); err != nil {
+		return nil, error2.ErrInvalidArgument.WithTemplate("validation", error2.Params{"reason": err})
+	}
+	user := req.Payload
+	if user.Id == 0 {
+		return nil, error2.ErrInvalidArgument.WithTemplate("validation", error2.Params{"reason": "id is required"})
+	}
+	fields := fieldmask.Paths(req.UpdateMask)
+	// Updated names and emails follow the same rules as on creation
+	if err := validateUserFields(user, fields); err != nil {
+		return nil, error2.ErrInvalidArgument.WithTemplate("validation", error2.Params{"reason": err})
+	}
+	if ifMatch := middleware.IfMatchFromContext(ctx); ifMatch != "" {
+		user.Etag = ifMatch
+	}
+
+	updatedUser, err := s.userUsecase.UpdateUser(ctx, user, fields)
+	if err != nil {
+		log.Error("Failed to update user", zap.Uint32("user_id", user.Id), zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("UserService.UpdateUser updated user", zap.Uint32("user_id", updatedUser.Id))
+	return &userpb.UpdateUserResponse{
+		User: updatedUser,
+	}, nil
+}
+
+// ListUsers implements the ListUsers RPC method
+func (s *UserServiceServer) ListUsers(ctx context.Context, req *userpb.ListUsersRequest) (*userpb.ListUsersResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("UserService.ListUsers called", zap.Int32("page_size", req.PageSize), zap.Bool("include_deleted", req.IncludeDeleted))
+
+	if err := req.Validate(); err != nil {
+		return nil, error2.ErrInvalidArgument.WithTemplate("validation", error2.Params{"reason": err})
+	}
+	if err := fieldmask.Validate(req.ReadMask, &userpb.UserEntity{}); err != nil {
+		return nil, error2.ErrInvalidArgument.WithTemplate("validation", error2.Params{"reason": err})
+	}
+	if req.IncludeDeleted && !middleware.IsAdmin(ctx) {
+		return nil, error2.ErrPermissionDenied.WithTemplate("admin_only", error2.Params{"option": "include_deleted"})
+	}
+
+	page, err := s.userUsecase.ListUsers(ctx, repository.ListOptions{
+		PageSize:       int(req.PageSize),
+		PageToken:      req.PageToken,
+		IncludeDeleted: req.IncludeDeleted,
+	}, fieldmask.Paths(req.ReadMask))
+	if err != nil {
+		log.Error("Failed to list users", zap.Error(err))
+		return nil, err
+	}
+
+	return &userpb.ListUsersResponse{
+		Users:         page.Items,
+		NextPageToken: page.NextPageToken,
+	}, nil
+}
+
+// DeleteUser implements the DeleteUser RPC method
+func (s *UserServiceServer) DeleteUser(ctx context.Context, req *userpb.DeleteUserRequest) (*userpb.DeleteUserResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("UserService.DeleteUser called", zap.Int64("user_id", req.Id))
+
+	if err := req.Validate(); err != nil {
+		return nil, error2.ErrInvalidArgument.WithTemplate("validation", error2.Params{"reason": err})
+	}
+
+	if err := s.userUsecase.DeleteUser(ctx, req.Id); err != nil {
+		log.Error("Failed to delete user", zap.Int64("user_id", req.Id), zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("UserService.DeleteUser deleted user", zap.Int64("user_id", req.Id))
+	return &userpb.DeleteUserResponse{}, nil
+}
+
+// RestoreUser implements the RestoreUser RPC method, which is admin only
+func (s *UserServiceServer) RestoreUser(ctx context.Context, req *userpb.RestoreUserRequest) (*userpb.RestoreUserResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("UserService.RestoreUser called", zap.Int64("user_id", req.Id))
+
+	if err := req.Validate(); err != nil {
+		return nil, error2.ErrInvalidArgument.WithTemplate("validation", error2.Params{"reason": err})
+	}
+	if !middleware.IsAdmin(ctx) {
+		return nil, error2.ErrPermissionDenied.WithTemplate("admin_only", error2.Params{"option": "RestoreUser"})
+	}
+
+	user, err := s.userUsecase.RestoreUser(ctx, req.Id)
+	if err != nil {
+		log.Error("Failed to restore user", zap.Int64("user_id", req.Id), zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("UserService.RestoreUser restored user", zap.Uint32("user_id", user.Id))
+	return &userpb.RestoreUserResponse{
+		User: user,
+	}, nil
+}
+
+// validateUserFields validates the name and email of user against the UserDTO rules, only
+// those in fields when given
+func validateUserFields(user *userpb.UserEntity, fields []string) error {
+	err := (&userpb.UserDTO{Name: user.Name, Email: user.Email}).ValidateAll()
+	var multiErr userpb.UserDTOMultiError
+	if !errors.As(err, &multiErr) {
+		return err
+	}
+	for _, fieldErr := range multiErr.AllErrors() {
+		var validationErr userpb.UserDTOValidationError
+		// Field is the Go name of the UserDTO field, e.g. Name for name
+		if len(fields) == 0 || !errors.As(fieldErr, &validationErr) || slices.Contains(fields, strings.ToLower(validationErr.Field())) {
+			return fieldErr
+		}
+	}
+	return nil
+}
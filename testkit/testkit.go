@@ -0,0 +1,150 @@
+// Package testkit runs the whole server in process for API tests. Every module is served
+// by the gRPC server with the production interceptor chain, over an in-memory bufconn
+// listener, and the HTTP gateway is served by httptest, so one table of cases can be run
+// against both protocols. Users are stored in memory unless a database is given.
+//
+//	func TestCreateUser(t *testing.T) {
+//		srv := testkit.Start(t)
+//		_, err := srv.User.CreateUser(context.Background(), &userpb.CreateUserRequest{...})
+//		...
+//		resp, err := srv.HTTP.Get(srv.URL + "/v1/users/1")
+//		...
+//	}
+package testkit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/harryosmar/protobuf-go/app"
+	hellopb "github.com/harryosmar/protobuf-go/gen/hello"
+	userpb "github.com/harryosmar/protobuf-go/gen/user"
+	"github.com/harryosmar/protobuf-go/modules"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/gorm"
+)
+
+// bufSize is the buffer of the in-memory gRPC listener
+const bufSize = 1 << 20
+
+// bufTarget is the gRPC target of the in-memory listener; passthrough skips name
+// resolution, since connections are made by the bufconn dialer
+const bufTarget = "passthrough:///bufconn"
+
+// Server is a running server with clients for both protocols
+type Server struct {
+	App   *app.App
+	Conn  *grpc.ClientConn // connection to the gRPC server, for clients of other services
+	Hello hellopb.HelloServiceClient
+	User  userpb.UserServiceClient
+	HTTP  *http.Client // client for the gateway at URL
+	URL   string       // base URL of the gateway, e.g. http://127.0.0.1:41234
+}
+
+// options collects the Options of Start
+type options struct {
+	args    []string
+	modules []app.Module
+	db      *gorm.DB
+}
+
+// Option configures Start
+type Option func(*options)
+
+// WithArgs sets configuration with command-line flags, e.g. "--admin-token=secret" or
+// "--rate-limit-enabled=false". They override the environment and the test defaults.
+func WithArgs(args ...string) Option {
+	return func(o *options) {
+		o.args = append(o.args, args...)
+	}
+}
+
+// WithModules serves modules instead of the hello and user modules
+func WithModules(modules ...app.Module) Option {
+	return func(o *options) {
+		o.modules = modules
+	}
+}
+
+// WithDatabase stores data in db, such as a MySQL test database, instead of memory. The
+// modules are migrated on it, and the caller stays responsible for closing it.
+func WithDatabase(db *gorm.DB) Option {
+	return func(o *options) {
+		o.db = db
+	}
+}
+
+// Start builds the server from the configuration defaults, the environment and the
+// WithArgs flags, starts it and stops it when the test ends. Logs are limited to errors
+// unless --log-level is given. It fails the test when the server cannot be built.
+func Start(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+
+	o := &options{modules: []app.Module{modules.NewHelloModule(), modules.NewUserModule()}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	storage := "--storage-backend=memory"
+	if o.db != nil {
+		storage = "--storage-backend=mysql"
+	}
+	args := append([]string{storage, "--log-level=error"}, o.args...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	builder := app.NewBuilder(args).WithModules(o.modules...)
+	if o.db != nil {
+		builder = builder.WithDatabase(o.db)
+	}
+	application, err := builder.Build(ctx)
+	if err != nil {
+		t.Fatalf("testkit: build server: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := application.Close(context.Background()); err != nil {
+			t.Errorf("testkit: close server: %v", err)
+		}
+	})
+
+	// gRPC over an in-memory listener
+	listener := bufconn.Listen(bufSize)
+	grpcServer := application.GRPCServer()
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	dialer := grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	})
+	conn, err := grpc.NewClient(bufTarget, grpc.WithTransportCredentials(insecure.NewCredentials()), dialer)
+	if err != nil {
+		t.Fatalf("testkit: dial gRPC server: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	// Gateway proxying to the same listener; its connections close when ctx is canceled
+	handler, err := application.HTTPHandler(ctx, bufTarget, dialer)
+	if err != nil {
+		t.Fatalf("testkit: build gateway: %v", err)
+	}
+	httpServer := httptest.NewServer(handler)
+	t.Cleanup(httpServer.Close)
+
+	return &Server{
+		App:   application,
+		Conn:  conn,
+		Hello: hellopb.NewHelloServiceClient(conn),
+		User:  userpb.NewUserServiceClient(conn),
+		HTTP:  httpServer.Client(),
+		URL:   httpServer.URL,
+	}
+}
@@ -0,0 +1,248 @@
+package testkit_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	userpb "github.com/harryosmar/protobuf-go/gen/user"
+	"github.com/harryosmar/protobuf-go/testkit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// result is the outcome of a call: the user returned on success, the gRPC code, and for
+// HTTP the response status
+type result struct {
+	user   *userpb.UserEntity
+	code   codes.Code
+	status int
+}
+
+// userAPI calls UserService over one protocol
+type userAPI interface {
+	create(t *testing.T, name, email string) result
+	get(t *testing.T, id uint32, ifNoneMatch string) result
+	patch(t *testing.T, id uint32, name, ifMatch string) result
+}
+
+func TestUserAPI(t *testing.T) {
+	srv := testkit.Start(t)
+	protocols := []struct {
+		name string
+		api  userAPI
+	}{
+		{"grpc", grpcAPI{srv.User}},
+		{"http", httpAPI{srv}},
+	}
+
+	tests := []struct {
+		name     string
+		httpOnly bool // conditional reads are an HTTP feature
+		call     func(t *testing.T, api userAPI, user *userpb.UserEntity) result
+		code     codes.Code
+		status   int
+		check    func(t *testing.T, got *userpb.UserEntity)
+	}{
+		{
+			name: "create duplicate email",
+			call: func(t *testing.T, api userAPI, user *userpb.UserEntity) result {
+				return api.create(t, "Other", user.Email)
+			},
+			code:   codes.AlreadyExists,
+			status: http.StatusConflict,
+		},
+		{
+			name: "get",
+			call: func(t *testing.T, api userAPI, user *userpb.UserEntity) result {
+				return api.get(t, user.Id, "")
+			},
+			code:   codes.OK,
+			status: http.StatusOK,
+			check: func(t *testing.T, got *userpb.UserEntity) {
+				if got.Name != "Ada" || got.Etag != "1" {
+					t.Errorf("got user %v, want name Ada and etag 1", got)
+				}
+			},
+		},
+		{
+			name: "get missing",
+			call: func(t *testing.T, api userAPI, user *userpb.UserEntity) result {
+				return api.get(t, user.Id+1000, "")
+			},
+			code:   codes.NotFound,
+			status: http.StatusNotFound,
+		},
+		{
+			name:     "get unchanged",
+			httpOnly: true,
+			call: func(t *testing.T, api userAPI, user *userpb.UserEntity) result {
+				return api.get(t, user.Id, `"1"`)
+			},
+			code:   codes.OK,
+			status: http.StatusNotModified,
+		},
+		{
+			name: "patch",
+			call: func(t *testing.T, api userAPI, user *userpb.UserEntity) result {
+				return api.patch(t, user.Id, "Ada Lovelace", `"1"`)
+			},
+			code:   codes.OK,
+			status: http.StatusOK,
+			check: func(t *testing.T, got *userpb.UserEntity) {
+				if got.Name != "Ada Lovelace" || got.Email == "" || got.Etag != "2" {
+					t.Errorf("got user %v, want the new name, the old email and etag 2", got)
+				}
+			},
+		},
+		{
+			name: "patch weak etag",
+			call: func(t *testing.T, api userAPI, user *userpb.UserEntity) result {
+				return api.patch(t, user.Id, "Ada Lovelace", `W/"1"`)
+			},
+			code:   codes.FailedPrecondition,
+			status: http.StatusPreconditionFailed,
+		},
+		{
+			name: "patch stale etag",
+			call: func(t *testing.T, api userAPI, user *userpb.UserEntity) result {
+				if r := api.patch(t, user.Id, "First", `"1"`); r.code != codes.OK {
+					t.Fatalf("first patch failed with %v", r.code)
+				}
+				return api.patch(t, user.Id, "Second", `"1"`)
+			},
+			code:   codes.FailedPrecondition,
+			status: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, protocol := range protocols {
+		for i, tt := range tests {
+			t.Run(protocol.name+"/"+tt.name, func(t *testing.T) {
+				if tt.httpOnly && protocol.name != "http" {
+					t.Skip("HTTP only")
+				}
+				created := protocol.api.create(t, "Ada", fmt.Sprintf("%s-%d@example.com", protocol.name, i))
+				if created.code != codes.OK || created.user.Id == 0 {
+					t.Fatalf("create = %v, %v; want a user", created.user, created.code)
+				}
+
+				got := tt.call(t, protocol.api, created.user)
+				if got.code != tt.code {
+					t.Fatalf("code = %v, want %v", got.code, tt.code)
+				}
+				if protocol.name == "http" && got.status != tt.status {
+					t.Fatalf("status = %d, want %d", got.status, tt.status)
+				}
+				if tt.check != nil {
+					tt.check(t, got.user)
+				}
+			})
+		}
+	}
+}
+
+// grpcAPI calls UserService with the gRPC client
+type grpcAPI struct {
+	client userpb.UserServiceClient
+}
+
+func (a grpcAPI) create(t *testing.T, name, email string) result {
+	resp, err := a.client.CreateUser(context.Background(), &userpb.CreateUserRequest{
+		User: &userpb.UserDTO{Name: name, Email: email},
+	})
+	return result{user: resp.GetUser(), code: status.Code(err)}
+}
+
+func (a grpcAPI) get(t *testing.T, id uint32, ifNoneMatch string) result {
+	resp, err := a.client.GetUser(context.Background(), &userpb.GetUserRequest{Id: int64(id)})
+	return result{user: resp.GetUser(), code: status.Code(err)}
+}
+
+func (a grpcAPI) patch(t *testing.T, id uint32, name, ifMatch string) result {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "if-match", ifMatch)
+	resp, err := a.client.UpdateUser(ctx, &userpb.UpdateUserRequest{
+		Payload:    &userpb.UserEntity{Id: id, Name: name},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+	})
+	return result{user: resp.GetUser(), code: status.Code(err)}
+}
+
+// httpAPI calls UserService through the gateway
+type httpAPI struct {
+	srv *testkit.Server
+}
+
+func (a httpAPI) create(t *testing.T, name, email string) result {
+	body := fmt.Sprintf(`{"user":{"name":%q,"email":%q}}`, name, email)
+	return a.do(t, http.MethodPost, "/v1/users", body, nil, &userpb.CreateUserResponse{})
+}
+
+func (a httpAPI) get(t *testing.T, id uint32, ifNoneMatch string) result {
+	var header http.Header
+	if ifNoneMatch != "" {
+		header = http.Header{"If-None-Match": {ifNoneMatch}}
+	}
+	return a.do(t, http.MethodGet, fmt.Sprintf("/v1/users/%d", id), "", header, &userpb.GetUserResponse{})
+}
+
+func (a httpAPI) patch(t *testing.T, id uint32, name, ifMatch string) result {
+	body := fmt.Sprintf(`{"name":%q}`, name)
+	header := http.Header{"If-Match": {ifMatch}}
+	return a.do(t, http.MethodPatch, fmt.Sprintf("/v1/users/%d", id), body, header, &userpb.UpdateUserResponse{})
+}
+
+// do sends a request and decodes a successful response into response, whose user field
+// is returned, or the gRPC code of an error response
+func (a httpAPI) do(t *testing.T, method, path, body string, header http.Header, response interface {
+	proto.Message
+	GetUser() *userpb.UserEntity
+}) result {
+	t.Helper()
+	req, err := http.NewRequest(method, a.srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := a.srv.HTTP.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: read body: %v", method, path, err)
+	}
+
+	got := result{status: resp.StatusCode}
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+	case resp.StatusCode < 300:
+		if err := protojson.Unmarshal(data, response); err != nil {
+			t.Fatalf("%s %s: decode %s: %v", method, path, data, err)
+		}
+		got.user = response.GetUser()
+		if etag := resp.Header.Get("ETag"); etag != `"`+got.user.GetEtag()+`"` {
+			t.Errorf("%s %s: ETag header %s, want the etag of %v", method, path, etag, got.user)
+		}
+	default:
+		var errorBody struct {
+			Code codes.Code `json:"code"`
+		}
+		if err := json.Unmarshal(data, &errorBody); err != nil {
+			t.Fatalf("%s %s: decode error %s: %v", method, path, data, err)
+		}
+		got.code = errorBody.Code
+	}
+	return got
+}
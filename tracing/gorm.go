@@ -0,0 +1,119 @@
+package tracing
+
+import (
+	"errors"
+	"regexp"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var (
+	// Literals are replaced so raw SQL never leaks values into spans
+	sqlStringLiteral  = regexp.MustCompile(`'(?:[^'\\]|\\.)*'`)
+	sqlNumericLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+)
+
+// GormPlugin emits a client span for every GORM operation
+type GormPlugin struct {
+	tracer trace.Tracer
+}
+
+// NewGormPlugin creates a GORM plugin using the global tracer provider
+func NewGormPlugin() *GormPlugin {
+	return &GormPlugin{tracer: otel.Tracer(TracerName)}
+}
+
+// Name implements gorm.Plugin
+func (p *GormPlugin) Name() string {
+	return "otel-tracing"
+}
+
+// Initialize implements gorm.Plugin by registering before/after callbacks around each operation
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	if err := callbacks.Create().Before("gorm:create").Register("otel:before_create", p.before("INSERT")); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("gorm:create").Register("otel:after_create", p.after); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("otel:before_query", p.before("SELECT")); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("gorm:query").Register("otel:after_query", p.after); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("otel:before_update", p.before("UPDATE")); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("otel:after_update", p.after); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("otel:before_delete", p.before("DELETE")); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:delete").Register("otel:after_delete", p.after); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("otel:before_row", p.before("ROW")); err != nil {
+		return err
+	}
+	if err := callbacks.Row().After("gorm:row").Register("otel:after_row", p.after); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().Before("gorm:raw").Register("otel:before_raw", p.before("RAW")); err != nil {
+		return err
+	}
+	return callbacks.Raw().After("gorm:raw").Register("otel:after_raw", p.after)
+}
+
+// before starts a span and stores it in the statement context
+func (p *GormPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement == nil || db.Statement.Context == nil {
+			return
+		}
+		ctx, _ := p.tracer.Start(db.Statement.Context, "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", db.Dialector.Name()),
+				attribute.String("db.operation", operation),
+			),
+		)
+		db.Statement.Context = ctx
+	}
+}
+
+// after records the sanitized statement, table, affected rows and error, then ends the span
+func (p *GormPlugin) after(db *gorm.DB) {
+	if db.Statement == nil || db.Statement.Context == nil {
+		return
+	}
+	span := trace.SpanFromContext(db.Statement.Context)
+	if !span.IsRecording() {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.statement", SanitizeSQL(db.Statement.SQL.String())),
+		attribute.String("db.sql.table", db.Statement.Table),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
+
+// SanitizeSQL replaces string and numeric literals with placeholders.
+// Statements built by GORM already use placeholders; this covers raw SQL.
+func SanitizeSQL(sql string) string {
+	sql = sqlStringLiteral.ReplaceAllString(sql, "?")
+	return sqlNumericLiteral.ReplaceAllString(sql, "?")
+}
@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/harryosmar/protobuf-go/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.uber.org/zap"
+)
+
+// Supported values for TRACING_EXPORTER
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// TracerName is the instrumentation name used for spans created by this application
+const TracerName = "github.com/harryosmar/protobuf-go"
+
+// ShutdownFunc flushes pending spans and releases exporter resources
+type ShutdownFunc func(ctx context.Context) error
+
+// InitTracing installs the global tracer provider and W3C trace context propagator.
+// The propagator is always installed so incoming traceparent headers are honored and
+// forwarded even when span export is disabled.
+// The OTLP exporter is configured through the standard OTEL_EXPORTER_OTLP_* environment variables.
+func InitTracing(ctx context.Context, cfg *config.Config, zapLogger *zap.Logger) (ShutdownFunc, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !cfg.TracingEnabled {
+		zapLogger.Info("Tracing disabled")
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closer, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.TracingExporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.AppName),
+		semconv.ServiceVersion(cfg.AppVersion),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TracingSampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	zapLogger.Info("Tracing enabled",
+		zap.String("exporter", cfg.TracingExporter),
+		zap.Float64("sample_ratio", cfg.TracingSampleRatio),
+	)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			if closeErr := closer.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}
+
+// newExporter creates the span exporter selected by TRACING_EXPORTER.
+// The returned closer is non-nil when the exporter owns a file.
+func newExporter(ctx context.Context, cfg *config.Config) (sdktrace.SpanExporter, io.Closer, error) {
+	switch cfg.TracingExporter {
+	case ExporterOTLP:
+		exporter, err := otlptracegrpc.New(ctx)
+		return exporter, nil, err
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		return exporter, nil, err
+	case ExporterFile:
+		file, err := os.OpenFile(cfg.TracingFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, err
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		return exporter, file, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported exporter %q", cfg.TracingExporter)
+	}
+}
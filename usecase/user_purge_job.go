@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/harryosmar/protobuf-go/logger"
+	"go.uber.org/zap"
+)
+
+// UserPurgeJob permanently deletes users that were deleted longer than the retention
+// period ago, after which they can no longer be restored
+type UserPurgeJob struct {
+	userUsecase UserUsecase
+	retention   time.Duration
+	batchSize   int
+}
+
+// NewUserPurgeJob creates a purge job keeping deleted users for retention
+func NewUserPurgeJob(userUsecase UserUsecase, retention time.Duration, batchSize int) *UserPurgeJob {
+	return &UserPurgeJob{
+		userUsecase: userUsecase,
+		retention:   retention,
+		batchSize:   batchSize,
+	}
+}
+
+// Run purges every interval until ctx is done. An interval of 0 disables the job.
+// Failures are logged and retried on the next tick.
+func (j *UserPurgeJob) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.PurgeOnce(ctx)
+		}
+	}
+}
+
+// PurgeOnce purges every user deleted before now minus the retention and returns how
+// many were purged
+func (j *UserPurgeJob) PurgeOnce(ctx context.Context) int64 {
+	log := logger.FromContext(ctx)
+	deletedBefore := time.Now().Add(-j.retention)
+
+	purged, err := j.userUsecase.PurgeDeletedUsers(ctx, deletedBefore, j.batchSize)
+	if err != nil && ctx.Err() == nil {
+		log.Error("Failed to purge deleted users", zap.Int64("purged", purged), zap.Error(err))
+		return purged
+	}
+	if purged > 0 {
+		log.Info("Purged deleted users", zap.Int64("purged", purged), zap.Time("deleted_before", deletedBefore))
+	}
+	return purged
+}
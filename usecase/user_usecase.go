@@ -2,21 +2,47 @@ package usecase
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"time"
 
 	error2 "github.com/harryosmar/protobuf-go/error"
+	"github.com/harryosmar/protobuf-go/etag"
+	"github.com/harryosmar/protobuf-go/fieldmask"
 	userpb "github.com/harryosmar/protobuf-go/gen/user"
+	"github.com/harryosmar/protobuf-go/logger"
 	"github.com/harryosmar/protobuf-go/repository"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
 )
 
 // UserUsecase defines the interface for user business logic
 type UserUsecase interface {
 	CreateUser(ctx context.Context, userDTO *userpb.UserDTO) (*userpb.UserEntity, error)
-	GetUserByID(ctx context.Context, id int64) (*userpb.UserEntity, error)
+	GetUserByID(ctx context.Context, id int64, includeDeleted bool, fields []string) (*userpb.UserEntity, error)
 	GetUserByEmail(ctx context.Context, email string) (*userpb.UserEntity, error)
-	UpdateUser(ctx context.Context, user *userpb.UserEntity) error
+	ListUsers(ctx context.Context, opts repository.ListOptions, fields []string) (*repository.Page[userpb.UserEntity], error)
+	UpdateUser(ctx context.Context, user *userpb.UserEntity, fields []string) (*userpb.UserEntity, error)
 	DeleteUser(ctx context.Context, id int64) error
+	RestoreUser(ctx context.Context, id int64) (*userpb.UserEntity, error)
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, batchSize int) (int64, error)
 }
 
+// userColumns maps the UserEntity fields to the users columns they are read from
+var userColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"email":      "email",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"deleted_at": "deleted_at",
+	"etag":       "version",
+}
+
+// userUpdatableFields are the UserEntity fields UpdateUser changes. id and etag identify
+// the user being updated and are accepted, but never updated.
+var userUpdatableFields = []string{"name", "email"}
+
 // userUsecase implements UserUsecase interface
 type userUsecase struct {
 	userRepo repository.UserRepository
@@ -31,52 +57,50 @@ func NewUserUsecase(userRepo repository.UserRepository) UserUsecase {
 
 // CreateUser handles the business logic for creating a user
 func (u *userUsecase) CreateUser(ctx context.Context, userDTO *userpb.UserDTO) (*userpb.UserEntity, error) {
-	// Create user entity from DTO
-	userEntity := &userpb.UserEntity{
-		Name:      userDTO.Name,
-		Email:     userDTO.Email,
-		CreatedAt: "", // Will be set by database
-		UpdatedAt: "", // Will be set by database
-	}
-
-	// Convert to ORM model for database operations
-	userORM, err := userEntity.ToORM(ctx)
-	if err != nil {
-		return nil, err
+	// Create user row from DTO; GORM stamps created_at and updated_at
+	userORM := &userpb.UserRecordORM{
+		Name:  userDTO.Name,
+		Email: userDTO.Email,
 	}
 
 	// Save to database using repository
-	if err := u.userRepo.Create(ctx, &userORM); err != nil {
+	if err := u.userRepo.Create(ctx, userORM); err != nil {
 		return nil, err
 	}
 
-	// Convert back to protobuf entity for response
-	createdUser, err := userORM.ToPB(ctx)
+	// Convert to protobuf entity for response
+	return toUserEntity(userORM), nil
+}
+
+// GetUserByID handles the business logic for retrieving a user by ID; deleted users are
+// only found with includeDeleted. Only the UserEntity fields in fields are read and
+// returned, all when empty.
+func (u *userUsecase) GetUserByID(ctx context.Context, id int64, includeDeleted bool, fields []string) (*userpb.UserEntity, error) {
+	ctx = logger.WithUserID(ctx, id)
+
+	columns, err := userReadColumns(fields)
 	if err != nil {
 		return nil, err
 	}
 
-	return &createdUser, nil
-}
-
-// GetUserByID handles the business logic for retrieving a user by ID
-func (u *userUsecase) GetUserByID(ctx context.Context, id int64) (*userpb.UserEntity, error) {
 	// Query database for user using repository
-	userORM, err := u.userRepo.GetByID(ctx, id)
+	getByID := u.userRepo.GetByID
+	if includeDeleted {
+		getByID = u.userRepo.GetByIDWithDeleted
+	}
+	userORM, err := getByID(ctx, id, columns...)
 	if err != nil {
 		return nil, err
 	}
 	if userORM == nil {
-		return nil, error2.ErrUserNotFound.WithMessage("user with ID %d not found", id)
+		return nil, error2.ErrUserNotFound.WithTemplate("by_id", error2.Params{"id": id})
 	}
 
-	// Convert ORM to protobuf entity
-	user, err := userORM.ToPB(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	return &user, nil
+	// Convert ORM to protobuf entity; a partial representation has its own etag
+	user := toUserEntity(userORM)
+	user.Etag = etag.ForFields(userORM.Version, fields)
+	fieldmask.Prune(user, fields)
+	return user, nil
 }
 
 // GetUserByEmail handles the business logic for retrieving a user by email
@@ -87,32 +111,183 @@ func (u *userUsecase) GetUserByEmail(ctx context.Context, email string) (*userpb
 		return nil, err
 	}
 	if userORM == nil {
-		return nil, error2.ErrUserNotFound.WithMessage("user with email %s not found", email)
+		return nil, error2.ErrUserNotFound.WithTemplate("by_email", error2.Params{"email": email})
 	}
 
 	// Convert ORM to protobuf entity
-	user, err := userORM.ToPB(ctx)
+	return toUserEntity(userORM), nil
+}
+
+// ListUsers handles the business logic for listing users by ID, returning only the
+// UserEntity fields in fields, all when empty
+func (u *userUsecase) ListUsers(ctx context.Context, opts repository.ListOptions, fields []string) (*repository.Page[userpb.UserEntity], error) {
+	columns, err := userReadColumns(fields)
+	if err != nil {
+		return nil, err
+	}
+	opts.Columns = columns
+
+	page, err := u.userRepo.List(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return &user, nil
+	users := make([]*userpb.UserEntity, 0, len(page.Items))
+	for _, userORM := range page.Items {
+		user := toUserEntity(userORM)
+		user.Etag = etag.ForFields(userORM.Version, fields)
+		fieldmask.Prune(user, fields)
+		users = append(users, user)
+	}
+
+	return &repository.Page[userpb.UserEntity]{Items: users, NextPageToken: page.NextPageToken}, nil
 }
 
-// UpdateUser handles the business logic for updating a user
-func (u *userUsecase) UpdateUser(ctx context.Context, user *userpb.UserEntity) error {
-	// Convert to ORM model for database operations
-	userORM, err := user.ToORM(ctx)
+// UpdateUser handles the business logic for updating a user's name and email, or only
+// the UserEntity fields in fields when given. A non-empty user.Etag is a condition (see
+// etag.MatchStrong) the stored user must satisfy, otherwise it fails with
+// ErrFailedPrecondition; a concurrent update between reading and saving the user fails
+// with ErrAborted.
+func (u *userUsecase) UpdateUser(ctx context.Context, user *userpb.UserEntity, fields []string) (*userpb.UserEntity, error) {
+	id := int64(user.Id)
+	ctx = logger.WithUserID(ctx, id)
+
+	columns, err := userUpdateColumns(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the stored row, bypassing the cache, so the precondition is not checked
+	// against a stale version
+	userORM, err := u.userRepo.GetByIDWithDeleted(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if userORM == nil || userORM.DeletedAt.Valid {
+		return nil, error2.ErrUserNotFound.WithTemplate("by_id", error2.Params{"id": id})
+	}
+	if user.Etag != "" && !etag.MatchStrong(user.Etag, etag.FromVersion(userORM.Version)) {
+		return nil, error2.ErrFailedPrecondition.WithTemplate("etag_mismatch", error2.Params{"etag": user.Etag})
+	}
+
+	for _, column := range columns {
+		switch column {
+		case "name":
+			userORM.Name = user.Name
+		case "email":
+			userORM.Email = user.Email
+		}
 	}
 
-	// Update in database using repository
-	return u.userRepo.Update(ctx, &userORM)
+	// Update in database using repository, only if nobody else updated it meanwhile
+	if err := u.userRepo.Update(ctx, userORM, columns...); err != nil {
+		return nil, err
+	}
+
+	return toUserEntity(userORM), nil
 }
 
 // DeleteUser handles the business logic for deleting a user
 func (u *userUsecase) DeleteUser(ctx context.Context, id int64) error {
-	// Delete from database using repository
+	ctx = logger.WithUserID(ctx, id)
+
+	// Soft-delete in database using repository; the purge job removes the row later
 	return u.userRepo.Delete(ctx, id)
 }
+
+// RestoreUser handles the business logic for undeleting a user that was not purged yet.
+// Restoring a live user returns it unchanged.
+func (u *userUsecase) RestoreUser(ctx context.Context, id int64) (*userpb.UserEntity, error) {
+	ctx = logger.WithUserID(ctx, id)
+
+	userORM, err := u.userRepo.GetByIDWithDeleted(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if userORM == nil {
+		return nil, error2.ErrUserNotFound.WithTemplate("by_id", error2.Params{"id": id})
+	}
+
+	if userORM.DeletedAt.Valid {
+		// Fails with ErrUserEmailExists when a live user took the email meanwhile
+		if err := u.userRepo.Restore(ctx, id); err != nil {
+			return nil, err
+		}
+		userORM.DeletedAt = gorm.DeletedAt{}
+		userORM.Version++
+	}
+
+	return toUserEntity(userORM), nil
+}
+
+// PurgeDeletedUsers permanently deletes users deleted before deletedBefore, batchSize rows
+// per statement, and returns how many were deleted
+func (u *userUsecase) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, batchSize int) (int64, error) {
+	var total int64
+	for {
+		purged, err := u.userRepo.Purge(ctx, deletedBefore, batchSize)
+		total += purged
+		if err != nil || purged < int64(batchSize) {
+			return total, err
+		}
+	}
+}
+
+// userReadColumns returns the users columns to read for fields, none (every column) when
+// fields is empty
+func userReadColumns(fields []string) ([]string, error) {
+	columns := make([]string, 0, len(fields))
+	for _, field := range fields {
+		column, ok := userColumns[field]
+		if !ok {
+			return nil, error2.ErrInvalidArgument.WithTemplate("validation", error2.Params{
+				"reason": fmt.Sprintf("unknown user field %q", field),
+			})
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// userUpdateColumns returns the users columns UpdateUser saves for fields, every
+// updatable one when fields is empty
+func userUpdateColumns(fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		fields = userUpdatableFields
+	}
+	columns := make([]string, 0, len(fields))
+	for _, field := range fields {
+		switch {
+		case slices.Contains(userUpdatableFields, field):
+			columns = append(columns, userColumns[field])
+		case field == "id" || field == "etag":
+			continue
+		default:
+			return nil, error2.ErrInvalidArgument.WithTemplate("validation", error2.Params{
+				"reason": fmt.Sprintf("user field %q cannot be updated", field),
+			})
+		}
+	}
+	if len(columns) == 0 {
+		return nil, error2.ErrInvalidArgument.WithTemplate("validation", error2.Params{
+			"reason": "update mask has no updatable field",
+		})
+	}
+	return columns, nil
+}
+
+// toUserEntity converts a user row to the API entity
+func toUserEntity(userORM *userpb.UserRecordORM) *userpb.UserEntity {
+	user := &userpb.UserEntity{
+		Id:        userORM.Id,
+		Name:      userORM.Name,
+		Email:     userORM.Email,
+		CreatedAt: timestamppb.New(userORM.CreatedAt),
+		UpdatedAt: timestamppb.New(userORM.UpdatedAt),
+		Etag:      etag.FromVersion(userORM.Version),
+	}
+	if userORM.DeletedAt.Valid {
+		user.DeletedAt = timestamppb.New(userORM.DeletedAt.Time)
+	}
+	return user
+}